@@ -0,0 +1,9 @@
+// Command simulate_chunks_in_vaults runs the simulation in pkg/sim, eg
+// go install ./cmd/simulate_chunks_in_vaults
+package main
+
+import "github.com/iancoleman/safe_chunk_responsibility_simulation/pkg/sim"
+
+func main() {
+	sim.Main()
+}
@@ -0,0 +1,9 @@
+// Command safe_chunk_responsibility_simulation runs the simulation in
+// pkg/sim, so go run . works from the root of the module.
+package main
+
+import "github.com/iancoleman/safe_chunk_responsibility_simulation/pkg/sim"
+
+func main() {
+	sim.Main()
+}
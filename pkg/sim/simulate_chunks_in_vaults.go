@@ -0,0 +1,6066 @@
+// Package sim simulates chunks being stored in vaults on the SAFE network.
+// Returns a csv list of vault names and total chunks stored.
+package sim
+
+import (
+	"bufio"
+	"context"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iancoleman/safe_chunk_responsibility_simulation/pkg/stats"
+	"github.com/iancoleman/safe_chunk_responsibility_simulation/pkg/strategy"
+)
+
+// Parameters
+
+var totalNodes int = 100
+
+// How many chunks are stored, and how many of the closest vaults store each
+// chunk, set with the total-stored and group-size flags.
+var totalStored int = 1000000
+var groupSize int = 8
+
+// How many relocations are triggered by each join or leave event, as happens
+// with node ageing. The whole part of the rate is always relocated and the
+// fractional part is the probability of one further relocation, so the
+// amount of churn scales with the size and activity of the network. Set with
+// the relocation-rate flag.
+var relocationRate float64 = 1.0
+
+// Which vaults are relocated after each join or leave.
+// - rate relocates relocationRate vaults chosen by the departure model
+// - ageing relocates a vault each time its age doubles, where age is the
+//   number of joins and leaves in its section since it first joined, so
+//   older vaults are relocated less often. Age is kept when relocating.
+const relocationSchedule = "rate"
+
+// How names for new / relocated vaults are chosen.
+// - uniform means vault names are spaced evenly, eg [10, 20, 30, 40]
+// - random means vault names are chosen randomly, eg [10, 11, 19, 33]
+// - bestfit aims to put the next vault into the largest space
+// - quietesthalf aims to put the next vault in the half with the least vaults
+// - emptysubsection finds any subsections with no vaults and places randomly
+//   in one of them.
+var namingStrategy = "bestfit"
+
+// How far into the largest gap bestfit places the next vault, as a divisor
+// of the gap trimmed from each end, eg 3 places it in the middle third.
+var bestFitDivisor uint64 = 3
+
+// How many leading bits quietesthalf uses to divide the network, eg 1 places
+// the next vault in the quieter half and 2 in the quietest quarter.
+var quietestDepth uint = 1
+
+// quietesthalf counts the vaults in each of 2^quietestDepth subsections, so
+// deeper than this the counts take more memory than the vaults themselves.
+const maxQuietestDepth uint = 20
+
+// How space between vaults is measured
+// - linear uses bigName - smallName
+// - xordistance uses bigName ^ smallName
+var spacingStrategy = "linear"
+
+// Strategies compared by the matrix flag, all run with the same seed
+var namingStrategies = []string{"uniform", "random", "bestfit", "quietesthalf", "emptysubsection"}
+var spacingStrategies = []string{"linear", "xordistance"}
+
+// Group sizes compared by the groupsize study, everything else held fixed
+var studyGroupSizes = []int{1, 2, 3, 4, 6, 8, 12, 16, 24, 32}
+
+// Network sizes compared by the networksize study for every naming strategy.
+// totalStored grows in proportion so each vault stores the same on average.
+// From the default 10000 chunks per vault, placing a chunk takes about 2µs
+// at 100 vaults, 16µs at 10000 and 500µs at 100000, so 10000 vaults take
+// about half an hour and 100000 vaults about five days for each naming
+// strategy, with a billion chunk names held to find collisions. Reduce
+// totalStored to run the largest size.
+var studyNetworkSizes = []int{100, 1000, 10000, 100000}
+
+// How the closest vaults to a chunk are found, which changes how fast chunks
+// are placed but never which vaults are chosen.
+// - heap keeps the closest vaults seen so far in a heap of groupSize, then
+//   sorts only those
+// - sort orders every vault in the section by xor distance
+// Vaults outside the group are left in a different order by each, so runs
+// that pick vaults by position, eg departures, differ between backends. sort
+// is the default so results don't change from before heap was added.
+var placementBackend = "sort"
+var placementBackends = []string{"sort", "heap"}
+
+// Which units to use for tracking storage
+// - chunks counts the number of chunks per vault
+// - megabytes counts the number of megabytes per vault since some chunks
+//   may be less than 1 MB in size
+const storageUnits = "megabytes"
+
+// How many leading bits of the name are used to group vaults into
+// subsections when reporting regional storage totals, eg 4 gives 16
+// subsections. Set with the subsection-depth flag.
+var subsectionDepth uint = 4
+
+// Every subsection is a row of the report, so deeper than this the report is
+// longer than a network could fill, and 64 would overflow the counts.
+const maxSubsectionDepth uint = 20
+
+// How many of the pairs of vaults expected to hold the most chunks in common
+// are reported.
+const coMembershipPairs int = 5
+
+// How many neighbours away in name order the load of vaults is compared, so
+// clusters of heavy vaults can be told apart from scattered ones.
+const autocorrelationLags int = 10
+
+// How many leading bits of the name define the section a vault belongs to,
+// eg 2 gives 4 sections. Chunks are routed to the section matching their
+// prefix and stored by the closest group of vaults within that section.
+// 0 means the whole network is one section.
+var sectionPrefixBits uint = 0
+
+// How many of the oldest vaults in each section are elders. Elders hold the
+// datamaps of the section and adults hold the chunks, so chunks are stored by
+// the closest groupSize adults. 0 means every vault holds both.
+const elderCount int = 0
+
+// How many leave and join events happen after all chunks are stored.
+// Departing vaults take their copies of chunks with them, which read-repair
+// then restores when those chunks are requested.
+const churnAfterStoring int = 0
+
+// How many vaults are relocated to a different section after all chunks are
+// stored. A relocated vault drops every chunk it held and takes a random
+// name in the new section, where it fetches the chunks it is now
+// responsible for. Needs sectionPrefixBits above 0.
+const crossSectionRelocations int = 0
+
+// How many GETs of random chunks happen between each churn event after
+// storing. A GET on a chunk with fewer than groupSize copies triggers
+// read-repair, copying it to the missing members of its closest group.
+const getRate int = 1000
+
+// GET rates compared by the repair-sweep flag, and how many churn events of
+// GETs at each rate are tried after churn stops before giving up on full
+// replication
+var repairGetRates = []int{0, 10, 100, 1000, 10000}
+
+const repairRecoveryEvents int = 1000
+
+// How many GETs happen once storing and churn are done, to measure the
+// temperature of chunks. Chunk popularity follows a Zipf distribution with
+// exponent getZipfExponent. Chunks fetched at least hotGets times are hot,
+// chunks never fetched are cold and the rest are warm. 0 means no GETs.
+const getPhaseRequests int = 0
+const getZipfExponent float64 = 1.1
+const hotGets int = 10
+
+// How many churn events after storing happen between refreshes, when every
+// group re-confirms its chunks and chunks held by vaults no longer
+// responsible for them are re-homed to the vaults that now are. 0 never
+// refreshes.
+var refreshInterval int = 0
+
+// How many vaults restart per churn event after storing, using the same
+// whole and fractional parts as relocationRate. A restart is not a leave
+// and join, so triggers no relocations.
+const restartRate float64 = 0.0
+
+// Churn rates and replica counts for the data-loss table produced with the
+// loss-trials flag. The churn rate is the fraction of vaults that leave
+// without any repair of the chunks they held, and each trial stores
+// lossTrialChunks chunks.
+var lossChurnRates = []float64{0.1, 0.25, 0.5}
+var lossReplicaCounts = []int{2, 4, 8}
+
+const lossTrialChunks int = 10000
+
+// Vault departures per chunk stored for the upload-churn flag, ie the ratio
+// of the churn rate to the upload rate. Each ratio is tested by storing
+// uploadChurnChunks chunks into a new network.
+var uploadChurnRatios = []float64{0.001, 0.01, 0.1, 1}
+
+const uploadChurnChunks int = 100000
+
+// The shrink flag stores shrinkChunks chunks then each round removes
+// shrinkLeaves vaults and adds shrinkJoins, refreshing chunks after every
+// round. A vault can hold shrinkCapacity times the average it held before
+// the network started shrinking.
+const shrinkChunks int = 100000
+const shrinkLeaves int = 10
+const shrinkJoins int = 5
+const shrinkCapacity float64 = 4
+
+// The split-threshold flag starts with totalNodes vaults in one section,
+// split until no section can split further, stores sectionChunks chunks then
+// adds another totalNodes vaults one at a time. With merge-threshold, vaults
+// then leave one at a time until one section or groupSize vaults remain.
+const sectionChunks int = 100000
+
+// Candidate strategy parameters tried by the tune flag, each scored by the
+// average standard deviation of storage over tuneTrials runs that store
+// tuneChunks chunks.
+var tuneBestFitDivisors = []uint64{2, 3, 4, 5, 6, 8}
+var tuneQuietestDepths = []uint{1, 2, 3, 4, 5, 6}
+
+const tuneChunks int = 100000
+const tuneTrials int = 3
+
+// How the network forms before the run
+// - none creates all totalNodes vaults before any chunks are stored
+// - exponential starts with growthStartNodes vaults and doubles the network
+//   each of growthSteps steps until it reaches totalNodes, storing an equal
+//   share of the chunks at every step. Vaults hand chunks over to new vaults
+//   responsible for them after each step.
+const growthModel = "none"
+const growthStartNodes int = 10
+const growthSteps int = 20
+
+// Which vaults leave the network when a relocation happens
+// - uniform means every vault is equally likely to leave
+// - young means newer vaults are more likely to leave, weighted by
+//   1 / (age + 1) where age is the number of joins since the vault joined.
+//   This matches real churn, where most departures are of recent vaults.
+var departureModel = "uniform"
+var departureModels = []string{"uniform", "young"}
+
+// How many messages the group agreeing on each join, leave and chunk store
+// sends. The group is the elders of the section when there are elders,
+// otherwise the closest groupSize vaults to the vault or chunk.
+// - none doesn't count messages
+// - alltoall has every member send its vote to every other member
+// - leader has the closest member propose to the others and collect their
+//   votes
+const consensusModel = "none"
+
+// Archive tier. Chunks stored more than archiveAfterChunks chunks ago are
+// cold and move off their close group to the archiveCopies closest of
+// archiveNodes archive vaults, which hold nothing else. 0 archiveNodes means
+// there is no archive tier. Not modelled with churn after storing.
+const archiveNodes int = 0
+const archiveAfterChunks int = 500000
+const archiveCopies int = 2
+
+// Store cost economics. Each vault can hold vaultCapacity storageUnits, and
+// 0 means there are no economics. Storing costs baseStoreCost per unit
+// divided by the fraction of the network's capacity that is spare, so
+// storing gets more expensive as the network fills, and the cost is shared
+// as farming reward by the vaults holding the copies. Cost and reward are
+// reported at economicsPoints points while storing.
+const vaultCapacity float64 = 0
+const baseStoreCost float64 = 1
+const economicsPoints int = 20
+
+// Chunks stored between checks for cancellation, batches are checked before
+// each batch instead
+const cancelCheckChunks int = 10000
+
+// Chunk names generated in a row that all collide before storing gives up,
+// which only happens when the hotspot has almost no unused names left
+const maxChunkNameAttempts int = 1000
+
+// Size of each entry in a datamap, in megabytes, ie the hashes and size of
+// one chunk of the file
+const datamapEntryMegabytes float64 = 0.0001
+
+// Exit codes, so scripts can tell bad parameters from failed runs
+const exitFailure int = 1
+const exitBadParameters int = 2
+
+// Flags
+
+var hotspot = flag.String("hotspot", "", "force a fraction of chunk names "+
+	"into a region of the address space, as prefix,fraction where prefix is "+
+	"hex, eg a3,0.2 puts 20% of chunks into names starting with a3")
+
+var lossTrials = flag.Int("loss-trials", 0, "instead of a single run, "+
+	"estimate the probability of any chunk losing all replicas for each "+
+	"churn rate and replica count using this many trials per estimate")
+
+var failureTrials = flag.Int("failure-trials", 0, "instead of a single "+
+	"run, store chunks with every naming strategy from the same seed and "+
+	"estimate the probability of any chunk losing every copy when k random "+
+	"vaults fail at once, for k up to twice groupSize, using this many "+
+	"trials per estimate")
+
+var routingTrials = flag.Int("routing-trials", 0, "instead of a single "+
+	"run, form the network with every naming strategy from the same seed "+
+	"and report how many hops this many messages take from a random "+
+	"client to the close group of a random name")
+
+var uploadChurn = flag.Bool("upload-churn", false, "instead of a single "+
+	"run, let vaults leave while chunks are being stored and report how "+
+	"often a chunk starts with fewer than groupSize copies")
+
+var shrink = flag.Bool("shrink", false, "instead of a single run, store "+
+	"chunks then lose vaults faster than they join, and report when vaults "+
+	"run out of capacity and when data is lost")
+
+var splitThreshold = flag.Int("split-threshold", 0, "instead of a single "+
+	"run, grow a network of sections that split when both halves would "+
+	"have at least this many vaults, and report the copies dropped by "+
+	"each split")
+
+var mergeThreshold = flag.Int("merge-threshold", 0, "with split-threshold, "+
+	"let vaults leave after growing, merging a section with its sibling "+
+	"when it has fewer than this many vaults, and report the chunks "+
+	"re-homed by each merge")
+
+var tune = flag.Bool("tune", false, "instead of a single run, search the "+
+	"parameters of the naming strategy for the lowest standard deviation of "+
+	"storage")
+
+var verify = flag.Float64("verify", 0, "check this fraction of chunk "+
+	"placements against a full sort of every vault by xor distance, and "+
+	"fail if any group differs")
+
+var seedFlag = flag.Int64("seed", 0, "seed for random numbers, to repeat "+
+	"an earlier run, 0 uses the current time")
+
+var sweep = flag.Int("sweep", 0, "instead of a single run, run this many "+
+	"consecutive seeds starting from the seed and report the most and least "+
+	"balanced")
+
+var dominance = flag.String("dominance", "1,2,5", "with sweep, comma "+
+	"separated percentages of all data, reporting the probability that one "+
+	"vault stores more than each")
+
+var matrix = flag.Bool("matrix", false, "instead of a single run, run "+
+	"every naming strategy with every spacing strategy from the same seed "+
+	"and compare them")
+
+var matrixSeeds = flag.Int("matrix-seeds", 1, "with matrix, run each "+
+	"pair of strategies from this many consecutive seeds and report how "+
+	"much the balance of storage varies between them")
+
+var study = flag.String("study", "", "instead of a single run, vary one "+
+	"parameter from the same seed and compare the balance of storage, "+
+	"groupsize, networksize or departure")
+
+var halfLife = flag.Int("half-life", 0, "instead of a single run, churn "+
+	"this many times after storing for every naming strategy from the same "+
+	"seed and compare how long chunks keep their close group")
+
+var handoff = flag.Int("handoff", 0, "instead of a single run, churn "+
+	"this many times after storing for every naming strategy from the same "+
+	"seed, where each chunk keeps the group it was stored by and a "+
+	"departing vault hands its chunks to the next closest vault and a "+
+	"joining vault takes the chunks it is closer to than the furthest "+
+	"holder, and compare with close groups recomputed for the final network")
+
+var repairSweep = flag.Int("repair-sweep", 0, "instead of a single run, "+
+	"churn this many times after storing for each GET rate from the same "+
+	"seed, and compare read-repair traffic and how many churn events of "+
+	"GETs restore every chunk to groupSize copies once churn stops")
+
+var churnLog = flag.String("churn-log", "", "write every join, leave and "+
+	"relocation to this file, as jsonl if the name ends in .jsonl otherwise "+
+	"as csv")
+
+var trace = flag.String("trace", "", "write every node added, node removed "+
+	"and chunk stored with its group to this file as jsonl")
+
+var replay = flag.String("replay", "", "instead of generating vaults and "+
+	"chunks, replay the node and chunk events of a trace file, placing each "+
+	"chunk with the current parameters")
+
+var namesFile = flag.String("names", "", "use the vault names in this file, "+
+	"one hex name per line, instead of generating them. Names longer than 16 "+
+	"hex characters are truncated to their leading 64 bits")
+
+var chunksFile = flag.String("chunks", "", "store the chunk names in this "+
+	"file, one hex name per line, instead of totalStored random names. Use - "+
+	"to read from stdin")
+
+var batchSize = flag.Int("batch-size", 0, "store chunks in batches of this "+
+	"size using preallocated buffers, for very large totalStored. Chunk "+
+	"name collisions are not checked since a set of every name would not fit "+
+	"in memory")
+
+var sample = flag.Int("sample", 0, "store only this many chunks and scale "+
+	"per-vault storage up to totalStored, reporting the sampling error of "+
+	"each vault")
+
+var assignments = flag.String("assignments", "", "write every chunk and "+
+	"the vaults holding it to this csv file")
+
+// Header of the assignments csv
+var assignmentsHeader = "chunk name," + storageUnits + ",holders"
+
+var progress = flag.String("progress", "", "with batch-size, append storage "+
+	"metrics to this csv file after every batch")
+
+func init() {
+	flag.IntVar(&totalStored, "total-stored", totalStored, "how many chunks "+
+		"are stored")
+	flag.IntVar(&groupSize, "group-size", groupSize, "how many of the "+
+		"closest vaults store each chunk")
+	flag.Float64Var(&relocationRate, "relocation-rate", relocationRate,
+		"how many vaults are relocated after each join or leave, where the "+
+			"fractional part is the chance of one more")
+	flag.UintVar(&subsectionDepth, "subsection-depth", subsectionDepth,
+		"how many leading bits of the name group vaults into subsections for "+
+			"the regional storage totals, eg 4 gives 16 subsections")
+	flag.StringVar(&placementBackend, "placement-backend", placementBackend,
+		"how the closest vaults to each chunk are found, sort or heap, which "+
+			"is much faster in large networks but leaves the other vaults in a "+
+			"different order, so departures differ from sort")
+}
+
+var sortBy = flag.String("sort-by", "name", "order of the vault report, "+
+	"name or stored (most loaded first)")
+
+var top = flag.Int("top", 0, "only list the n most loaded and n least "+
+	"loaded vaults in the vault report")
+
+var format = flag.String("format", "csv", "how to print the parameters, "+
+	"vault report and summary metrics, csv, tsv for pasting into "+
+	"spreadsheets or markdown for pasting into forum posts")
+
+var decimals = flag.Int("decimals", -1, "print decimal numbers in the "+
+	"parameters, vault report and summary metrics with this many places, "+
+	"never in scientific notation and always with . as the separator, -1 "+
+	"prints them as precisely as needed")
+
+var nameFormat = flag.String("name-format", "hex", "how names are printed, "+
+	"hex, base32, binary or xorname, which is 64 hex characters like the "+
+	"XorNames of safe_network with the bits beyond the first 64 as zero. "+
+	"base32 digits 2 to 7 come after z, so base32 names don't sort in name "+
+	"order. Trace files always use hex so they can be replayed")
+
+var outdir = flag.String("outdir", "", "also write params.json, "+
+	"vaults.csv, spacings.csv, events.jsonl and summary.json to this directory")
+
+var plotScript = flag.Bool("plot-script", false, "with outdir, also write "+
+	"plot.py, which draws the standard charts from vaults.csv and "+
+	"spacings.csv with matplotlib")
+
+var tidy = flag.String("tidy", "", "write the vault report to this csv "+
+	"file with every parameter of the run on every row, so files from many "+
+	"runs can be concatenated")
+
+var sqlitePath = flag.String("sqlite", "", "append the parameters, summary "+
+	"metrics and vault report of the run to this SQLite database, which is "+
+	"created if it doesn't exist")
+
+var parquetPath = flag.String("parquet", "", "write the vault report to "+
+	"this parquet file, with every run of sweep or matrix in the same file")
+
+var paramsStdin = flag.Bool("params-stdin", false, "instead of a single "+
+	"run, read a json object of parameters per line from stdin and write a "+
+	"json result per line, with missing parameters taken from the flags")
+
+var maxDuration = flag.Duration("max-duration", 0, "stop storing chunks "+
+	"after this long, eg 10m, and report partial results")
+
+var convergeWindow = flag.Int("converge-window", 0, "stop storing chunks "+
+	"once no vault's share of everything stored has changed by more than "+
+	"converge-threshold over this many chunks, and report how many were "+
+	"needed")
+
+var convergeThreshold = flag.Float64("converge-threshold", 0.01, "with "+
+	"converge-window, the largest relative change in a vault's share of "+
+	"storage that counts as converged")
+
+var chunksPerFile = flag.Int("chunks-per-file", 0, "store a datamap for "+
+	"every this many chunks, held by the group closest to the datamap name "+
+	"and reported separately from chunk storage")
+
+var restartPolicy = flag.String("restart-policy", "same", "whether a "+
+	"restarted vault returns with its old name and data (same) or with a new "+
+	"name and no data (new)")
+
+var relocationRetention = flag.String("relocation-retention", "drop",
+	"whether a relocated vault keeps the chunks it is still responsible for "+
+		"at its new name (keep) or drops everything (drop)")
+
+var chunkSizes = flag.String("chunk-sizes", "", "use the distribution of "+
+	"chunk sizes in this csv file of cumulative probability,min size in MB "+
+	"instead of the built in one, eg as written by the fit-sizes subcommand")
+
+var roles = flag.Bool("roles", false, "treat the closest vault to each chunk "+
+	"as the primary holder and the rest of the group as replicas, and report "+
+	"primary and replica storage separately")
+
+// Distribution of chunk sizes taken from
+// https://safenetforum.org/t/traffic-sizes-on-the-safe-network/22213
+// unless the chunk-sizes flag loads another one.
+// A chunk is in the first bucket with a cumulative probability above a random
+// number, and is sized evenly between MinSize and MinSize + 0.1 MB. Chunks
+// beyond the last bucket are 1 MB.
+type ChunkSizeBucket struct {
+	CumulativeProbability float64
+	MinSize               float64
+}
+
+var chunkSizeBuckets = []ChunkSizeBucket{
+	{0.709159, 0.0}, // between 0-100 KB
+	{0.774634, 0.1}, // between 100-200 KB
+	{0.777539, 0.2}, // between 200-300 KB
+	{0.778139, 0.3}, // between 300-400 KB
+	{0.778459, 0.4}, // between 400-500 KB
+	{0.779100, 0.5}, // between 500-600 KB
+	{0.779342, 0.6}, // between 600-700 KB
+	{0.779450, 0.7}, // between 700-800 KB
+	{0.779588, 0.8}, // between 800-900 KB
+	{0.779730, 0.9}, // between 900-1000 KB
+}
+
+// Structs
+
+type Node struct {
+	Name   uint64
+	Stored float64
+	// Chunks is the exact number of chunks held, whatever the storageUnits
+	Chunks uint64
+	Joined int
+	// HotspotStored is the part of Stored that came from hotspot chunks
+	HotspotStored float64
+	// PrimaryStored is the part of Stored held as the closest vault to the
+	// chunk, the remainder being held as a replica
+	PrimaryStored float64
+	// MetadataStored is datamaps held, accounted separately from Stored
+	MetadataStored float64
+	// RefreshTraffic is chunk data sent and received when refreshing
+	RefreshTraffic float64
+	// Elder is set for the oldest vaults in each section when there are
+	// elders
+	Elder bool
+	// Age is joins and leaves in the section of the vault since it first
+	// joined, with the ageing relocation schedule
+	Age int
+	// Messages is consensus messages sent and received
+	Messages int
+	// ColdStored is the part of Stored never fetched in the GET phase
+	ColdStored float64
+	// Reward is farming reward earned for storing chunks
+	Reward float64
+	// ExpectedShare is the fraction of all chunk copies the vault is
+	// expected to hold, given its name and uniform chunk names
+	ExpectedShare float64
+	// ExpectedStored is the ExpectedShare of everything stored, so Stored
+	// beyond it is placement noise rather than the spacing of names
+	ExpectedStored float64
+	// Gaps is the spacing from the previous vault name to the next, by
+	// each of spacingStrategies
+	Gaps []uint64
+}
+
+// Chunk is a stored chunk and the vaults holding a copy of it, only kept
+// when there is churn after storing.
+type Chunk struct {
+	Name    uint64
+	Amount  float64
+	Holders []uint64
+	Hotspot bool
+	// Gets is how many times the chunk was fetched in the GET phase
+	Gets int
+}
+
+// Section is the vaults and chunks whose names start with the leading bits
+// of Prefix.
+type Section struct {
+	Prefix uint64
+	Bits   uint
+}
+
+// EconomicsPoint is store cost and farming reward once Chunks chunks have
+// been stored.
+type EconomicsPoint struct {
+	Chunks        int
+	Spare         float64
+	StoreCost     float64
+	UploadCost    float64
+	AverageReward float64
+	Deviation     float64
+}
+
+// RelocationMove is the data a relocated vault was responsible for, which
+// has to be transferred again after the relocation.
+type RelocationMove struct {
+	Name  uint64
+	Moved float64
+	// Kept is data the vault is still responsible for at its new name,
+	// when relocated vaults retain chunks
+	Kept float64
+}
+
+// ChurnEvent is a join, leave or relocation, where Time is the number of
+// churn events before this one.
+type ChurnEvent struct {
+	Time        int    `json:"time"`
+	Event       string `json:"event"`
+	Name        string `json:"name"`
+	NewName     string `json:"new_name,omitempty"`
+	NetworkSize int    `json:"network_size"`
+}
+
+// Invalid parameters or input, as opposed to a failure while running
+type ParameterError string
+
+func (e ParameterError) Error() string {
+	return string(e)
+}
+
+// Metric is a reported parameter or summary metric, kept in order so the
+// markdown tables and results files list them as they were printed.
+type Metric struct {
+	Name  string
+	Value interface{}
+	// Ideal is the value for perfectly uniform names or perfectly equal
+	// load, nil when there isn't one
+	Ideal interface{}
+}
+
+// TraceEvent is a line of the trace. Name is the vault name for
+// node_added and node_removed, or the chunk name for chunk_stored along with
+// the Amount stored by each vault in its Group.
+type TraceEvent struct {
+	Event  string   `json:"event"`
+	Name   string   `json:"name"`
+	Amount float64  `json:"amount,omitempty"`
+	Group  []string `json:"group,omitempty"`
+}
+
+// RunParams is one run read from a json line of params-stdin or posted to
+// serve. Parameters missing from the json are taken from the flags.
+type RunParams struct {
+	Seed            int64  `json:"seed"`
+	TotalNodes      int    `json:"totalNodes"`
+	TotalStored     int    `json:"totalStored"`
+	GroupSize       int    `json:"groupSize"`
+	NamingStrategy  string `json:"namingStrategy"`
+	BestFitDivisor  uint64 `json:"bestFitDivisor"`
+	QuietestDepth   uint   `json:"quietestDepth"`
+	SpacingStrategy string `json:"spacingStrategy"`
+}
+
+// RunResult is the balance of storage for a RunParams, or the Error that
+// stopped it running, in which case the metrics are zero.
+type RunResult struct {
+	Params                    *RunParams `json:"params,omitempty"`
+	Error                     string     `json:"error,omitempty"`
+	StandardDeviation         float64    `json:"standardDeviation"`
+	RelativeStandardDeviation float64    `json:"relativeStandardDeviation"`
+	Gini                      float64    `json:"gini"`
+	MaxOverAverage            float64    `json:"maxOverAverage"`
+	MinOverAverage            float64    `json:"minOverAverage"`
+}
+
+// Job is a run submitted to the serve subcommand. Status is queued, running,
+// done or failed, and Result is set once it is done or failed.
+type Job struct {
+	ID     int        `json:"id"`
+	Status string     `json:"status"`
+	Params RunParams  `json:"params"`
+	Result *RunResult `json:"result,omitempty"`
+}
+
+// JobQueue holds the runs submitted to serve, which a fixed number of
+// workers take in order. finished is closed and replaced whenever a run
+// finishes, waking anything waiting for results. Runs larger than maxNodes
+// or maxStored are refused and runs longer than timeout fail, so one run
+// can't hold a worker forever.
+type JobQueue struct {
+	mutex     sync.Mutex
+	jobs      []*Job
+	queue     chan *Job
+	seed      int64
+	finished  chan struct{}
+	timeout   time.Duration
+	maxNodes  int
+	maxStored int
+}
+
+// VaultWriter writes the vault report of each run to a file that is only
+// complete once closed.
+type VaultWriter interface {
+	WriteVaults(nodes []Node, shares map[uint64]float64, seed int64) error
+	Close() error
+}
+
+// Counters
+
+// joins counts every vault that has joined the network, used to age vaults
+var joins int = 0
+
+// Name collisions found and regenerated, which are only expected to happen
+// for random names in a small address space
+var vaultNameCollisions int = 0
+var chunkNameCollisions int = 0
+
+// nameIndex holds the names of the current vaults in order, so a new vault
+// is inserted with a binary search instead of sorting every name again
+var nameIndex []uint64 = []uint64{}
+
+// quietestVaults counts the names in nameIndex in each subsection at
+// quietestDepth, kept with the index so quietesthalf doesn't count every
+// name for each join
+var quietestVaults []int = []int{}
+
+// Read-repair of chunks that lost copies to churn after storing
+var repairTraffic float64 = 0
+var chunksRepaired int = 0
+var refreshes int = 0
+var refreshConfirmations int = 0
+var chunksRehomed int = 0
+var restarts int = 0
+var restartDropped float64 = 0
+var chunksLost int = 0
+var underReplicatedAfterEvent []int = []int{}
+
+// Stored chunks whose close group changed with each join or leave of churn
+// after storing
+var groupChangesPerEvent []float64 = []float64{}
+
+// The churn event at which the close group of each stored chunk first
+// changed, by a join, leave or relocation, or 0 if it has not changed
+var groupFirstChanged []int = []int{}
+
+// Relocations by the age of the vault when relocated, and joins and leaves
+// counted towards ages, with the ageing relocation schedule
+var relocationsByAge map[int]int = map[int]int{}
+var ageingEvents int = 0
+
+// Messages sent by groups agreeing on membership changes and chunk stores
+var consensusMessages int = 0
+
+// Archive tier vaults, and cold chunks moved to them from close groups
+var archive []Node = nil
+var chunksArchived int = 0
+var archivedFromCloseGroups float64 = 0
+
+// Capacity used and paid for, and cost and reward while storing, when
+// vaults have a capacity
+var capacityUsed float64 = 0
+var uploadCost float64 = 0
+var economicsInterval int = 0
+var economics []EconomicsPoint = []EconomicsPoint{}
+
+// Chunks moved by relocating vaults between sections
+var relocationDropped float64 = 0
+var relocationFetched float64 = 0
+
+// Every relocation after chunks are stored, in order
+var relocationMoves []RelocationMove = []RelocationMove{}
+
+// Churn log output, nil unless the churn-log flag is set. Events are
+// written as they happen, as jsonl with churnLogEncoder or otherwise csv.
+var churnLogWriter *bufio.Writer = nil
+var churnLogEncoder *json.Encoder = nil
+var churnEventCount int = 0
+
+// Assignments output, only set while chunks are placed if no chunks are
+// kept, so each chunk is written when placed instead
+var assignmentsWriter *bufio.Writer = nil
+
+// Trace output, nil unless the trace flag is set
+var traceWriter *bufio.Writer = nil
+var traceEncoder *json.Encoder = nil
+
+// Parquet output, nil unless the parquet flag is set
+var parquetVaults VaultWriter = nil
+
+// Chunk names read from the chunks flag, nil when generating names. They are
+// read once and stored again by every run in modes with many runs.
+var chunkNamesInput []uint64 = nil
+
+// Random numbers for the whole simulation, reseeded in main so any run can be
+// repeated from its seed
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// Sorter reused for every placement so finding the closest group does not
+// allocate
+var xorSorter = &ByXorDistance{}
+
+// Random numbers choosing which placements to verify, separate from rng so
+// verifying never changes the results of a seed
+var verifyRng = rand.New(rand.NewSource(0))
+
+// Placements checked against a full sort and those that differed, with the
+// first chunk to differ
+var placementsVerified int = 0
+var placementMismatches int = 0
+var firstMismatchChunk uint64 = 0
+
+// How much sampled storage was scaled by, 0 when not sampling
+var sampleScale float64 = 0
+
+// Every chunk placed, to check the copies held by vaults add up
+var chunksStored int = 0
+
+// Chunks stored while the network was smaller than totalNodes
+var chunksStoredWhileGrowing int = 0
+
+// Set when max-duration stopped the run early, so results are partial
+var placementStopped bool = false
+
+// Set when converge-window stopped the run because shares of storage had
+// settled, and the share of each vault when last checked
+var placementConverged bool = false
+var convergedShares map[uint64]float64 = map[uint64]float64{}
+
+// Chunks and the vaults holding them at the end of the simulation, only kept
+// when an option needs them
+var assignedChunks []Chunk = nil
+
+// Parameters and summary metrics in the order they were reported, kept for
+// markdown tables and the results directory
+var parameters []Metric = nil
+var summaryMetrics []Metric = nil
+
+// Hotspot region parsed from the hotspot flag, with hotspotBits of zero
+// meaning there is no hotspot.
+var hotspotPrefix uint64 = 0
+var hotspotBits uint = 0
+var hotspotFraction float64 = 0
+
+// Sorters
+
+// ByXorDistance sorts nodes by distance to Target, so the target is kept
+// once here rather than in every node.
+type ByXorDistance struct {
+	Nodes  []Node
+	Target uint64
+}
+
+func (a ByXorDistance) Len() int      { return len(a.Nodes) }
+func (a ByXorDistance) Swap(i, j int) { a.Nodes[i], a.Nodes[j] = a.Nodes[j], a.Nodes[i] }
+func (a ByXorDistance) Less(i, j int) bool {
+	return a.Nodes[i].Name^a.Target < a.Nodes[j].Name^a.Target
+}
+
+type ByNodeName []Node
+
+func (a ByNodeName) Len() int           { return len(a) }
+func (a ByNodeName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByNodeName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+type ByStored []Node
+
+func (a ByStored) Len() int           { return len(a) }
+func (a ByStored) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByStored) Less(i, j int) bool { return a[i].Stored > a[j].Stored }
+
+type ByJoined []Node
+
+func (a ByJoined) Len() int           { return len(a) }
+func (a ByJoined) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByJoined) Less(i, j int) bool { return a[i].Joined < a[j].Joined }
+
+type ByName []uint64
+
+func (a ByName) Len() int           { return len(a) }
+func (a ByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByName) Less(i, j int) bool { return a[i] < a[j] }
+
+// Functions
+
+// serveBrowser is set by the WebAssembly build, which serves simulations to
+// the page instead of running from flags
+var serveBrowser func() = nil
+
+// appendToDatabase is set by builds with SQLite output
+var appendToDatabase func(filename string, nodes []Node, shares map[uint64]float64) error = nil
+
+// newParquetVaults is set by builds with parquet output
+var newParquetVaults func(filename string) (VaultWriter, error) = nil
+
+// serveGRPC is set by builds with the gRPC API, and serves the runs in jobs
+// until the context is done
+var serveGRPC func(ctx context.Context, addr string, jobs *JobQueue) error = nil
+
+// Returned when a run is submitted to serve with every queue place taken
+var errQueueFull = errors.New("Queue is full")
+
+// Main runs the simulation with the command line flags and exits with a
+// non-zero code if it fails.
+func Main() {
+	// environment variables are read first so flags override them
+	err := flagsFromEnvironment(flag.CommandLine, "SAFE_SIM_")
+	flag.Parse()
+	if serveBrowser != nil {
+		serveBrowser()
+		return
+	}
+	// interrupting stops the run cleanly, flushing anything written so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if err == nil {
+		err = run(ctx)
+	}
+	stop()
+	if err == nil {
+		// markdown collects the metrics of every mode into one table
+		printMetrics()
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		err = errors.New("Interrupted")
+	}
+	fmt.Fprintln(os.Stderr, err)
+	if _, ok := err.(ParameterError); ok {
+		os.Exit(exitBadParameters)
+	}
+	os.Exit(exitFailure)
+}
+
+func flagsFromEnvironment(flags *flag.FlagSet, prefix string) error {
+	// Sets each flag from an environment variable named by the prefix and
+	// the flag name in upper case with - as _, eg SAFE_SIM_TOTAL_STORED.
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		name := prefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok || err != nil {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = ParameterError("Invalid " + name + ": " + setErr.Error())
+		}
+	})
+	return err
+}
+
+func run(ctx context.Context) error {
+	// parameters are checked before anything runs
+	err := checkParameters()
+	if err != nil {
+		return err
+	}
+	if *hotspot != "" {
+		hotspotPrefix, hotspotBits, hotspotFraction, err = parseHotspot(*hotspot)
+		if err != nil {
+			return err
+		}
+	}
+	if *chunkSizes != "" {
+		chunkSizeBuckets, err = readChunkSizeBuckets(*chunkSizes)
+		if err != nil {
+			return err
+		}
+	}
+	// set up random numbers
+	seed := *seedFlag
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	startRun(seed)
+	// driven by an orchestrator, so nothing else goes to stdout
+	if *paramsStdin {
+		return runFromStdin(ctx, seed)
+	}
+	// report the starting parameters
+	if *format == "markdown" {
+		printHeader("parameter", "value")
+	}
+	printParam("seed", seed)
+	printParam("totalNodes", totalNodes)
+	printParam("totalStored", totalStored)
+	printParam("groupSize", groupSize)
+	printParam("namingStrategy", namingStrategy)
+	printParam("bestFitDivisor", bestFitDivisor)
+	printParam("quietestDepth", quietestDepth)
+	printParam("spacingStrategy", spacingStrategy)
+	printParam("storageUnits", storageUnits)
+	printParam("relocationRate", relocationRate)
+	printParam("relocationSchedule", relocationSchedule)
+	printParam("growthModel", growthModel)
+	printParam("departureModel", departureModel)
+	printParam("consensusModel", consensusModel)
+	printParam("archiveNodes", archiveNodes)
+	printParam("archiveAfterChunks", archiveAfterChunks)
+	printParam("archiveCopies", archiveCopies)
+	printParam("vaultCapacity", vaultCapacity)
+	printParam("baseStoreCost", baseStoreCost)
+	printParam("hotspot", *hotspot)
+	printParam("replay", *replay)
+	printParam("names", *namesFile)
+	printParam("chunks", *chunksFile)
+	printParam("batchSize", *batchSize)
+	printParam("sample", *sample)
+	printParam("roles", *roles)
+	printParam("subsectionDepth", subsectionDepth)
+	printParam("sectionPrefixBits", sectionPrefixBits)
+	printParam("elderCount", elderCount)
+	printParam("crossSectionRelocations", crossSectionRelocations)
+	printParam("churnAfterStoring", churnAfterStoring)
+	printParam("getRate", getRate)
+	printParam("getPhaseRequests", getPhaseRequests)
+	printParam("refreshInterval", refreshInterval)
+	printParam("restartRate", restartRate)
+	printParam("restartPolicy", *restartPolicy)
+	printParam("relocationRetention", *relocationRetention)
+	printParam("maxDuration", *maxDuration)
+	printParam("convergeWindow", *convergeWindow)
+	printParam("convergeThreshold", *convergeThreshold)
+	printParam("chunksPerFile", *chunksPerFile)
+	printParam("chunkSizes", *chunkSizes)
+	// separate results files, with the trace as the event log
+	if *outdir != "" {
+		err := os.MkdirAll(*outdir, 0755)
+		if err != nil {
+			return errors.New("Cannot create outdir: " + err.Error())
+		}
+		if *trace == "" {
+			*trace = filepath.Join(*outdir, "events.jsonl")
+		}
+	}
+	// trace of every event, written as it happens
+	if *trace != "" {
+		f, err := os.Create(*trace)
+		if err != nil {
+			return errors.New("Cannot create trace: " + err.Error())
+		}
+		defer f.Close()
+		traceWriter = bufio.NewWriter(f)
+		traceEncoder = json.NewEncoder(traceWriter)
+		defer traceWriter.Flush()
+	}
+	// population dynamics, written as they happen
+	if *churnLog != "" {
+		f, err := os.Create(*churnLog)
+		if err != nil {
+			return errors.New("Cannot create churn log: " + err.Error())
+		}
+		defer f.Close()
+		churnLogWriter = bufio.NewWriter(f)
+		defer churnLogWriter.Flush()
+		if filepath.Ext(*churnLog) == ".jsonl" {
+			churnLogEncoder = json.NewEncoder(churnLogWriter)
+		} else {
+			fmt.Fprintln(churnLogWriter, "time,event,vault name,new vault name,network size")
+		}
+	}
+	// sections splitting as the network grows
+	if *splitThreshold > 0 {
+		return reportSplits(ctx)
+	}
+	// shrinking network
+	if *shrink {
+		return reportShrinking(ctx)
+	}
+	// churn while storing
+	if *uploadChurn {
+		return reportUploadChurn(ctx)
+	}
+	// routing
+	if *routingTrials > 0 {
+		return reportRouting(ctx, seed, *routingTrials)
+	}
+	// simultaneous failures
+	if *failureTrials > 0 {
+		return reportFailures(ctx, seed, *failureTrials)
+	}
+	// durability mode
+	if *lossTrials > 0 {
+		reportDataLoss(*lossTrials)
+		return nil
+	}
+	// chunk names from a file or stdin
+	if *chunksFile == "-" {
+		chunkNamesInput, err = readChunkNames(os.Stdin)
+	} else if *chunksFile != "" {
+		var f *os.File
+		f, err = os.Open(*chunksFile)
+		if err != nil {
+			return errors.New("Cannot open chunks: " + err.Error())
+		}
+		chunkNamesInput, err = readChunkNames(f)
+		f.Close()
+	}
+	if err != nil {
+		return err
+	}
+	// subcommands
+	if flag.Arg(0) == "analyze" {
+		return analyze(ctx, flag.Args()[1:])
+	}
+	if flag.Arg(0) == "query" {
+		return query(ctx, flag.Args()[1:])
+	}
+	if flag.Arg(0) == "bench" {
+		return bench(ctx, flag.Args()[1:])
+	}
+	if flag.Arg(0) == "import-log" {
+		return importLog(flag.Args()[1:])
+	}
+	if flag.Arg(0) == "fit-sizes" {
+		return fitSizes(flag.Args()[1:])
+	}
+	if flag.Arg(0) == "serve" {
+		return serve(ctx, seed, flag.Args()[1:])
+	}
+	// cached groups with handoff
+	if *handoff > 0 {
+		return reportHandoff(ctx, seed, *handoff)
+	}
+	// read-repair at different GET rates
+	if *repairSweep > 0 {
+		return reportRepairSweep(ctx, seed, *repairSweep)
+	}
+	// placement stability mode
+	if *halfLife > 0 {
+		return reportHalfLife(ctx, seed, *halfLife)
+	}
+	// vault rows of every run, only complete once closed
+	if *parquetPath != "" {
+		parquetVaults, err = newParquetVaults(*parquetPath)
+		if err != nil {
+			return err
+		}
+		defer closeParquetVaults()
+	}
+	// seed sweep mode
+	if *sweep > 0 {
+		err = reportSweep(ctx, seed, *sweep)
+		if err != nil {
+			return err
+		}
+		return closeParquetVaults()
+	}
+	// strategy comparison mode
+	if *matrix {
+		err = reportMatrix(ctx, seed)
+		if err != nil {
+			return err
+		}
+		return closeParquetVaults()
+	}
+	// scaling studies
+	if *study != "" {
+		return reportStudy(ctx, *study, seed)
+	}
+	// tuning mode
+	if *tune {
+		return reportTuning(ctx)
+	}
+	if *maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *maxDuration)
+		defer cancel()
+	}
+	nodes, err := simulatedNodes(ctx)
+	if err != nil {
+		return err
+	}
+	// which vaults hold each chunk
+	if *assignments != "" && assignedChunks != nil {
+		err = writeAssignments(*assignments, assignedChunks)
+		if err != nil {
+			return err
+		}
+	}
+	// report
+	sort.Sort(ByNodeName(nodes))
+	shares := map[uint64]float64{}
+	for i, share := range getKeyspaceShares(nodes) {
+		shares[nodes[i].Name] = share
+	}
+	setExpectedShares(nodes)
+	setGaps(nodes)
+	if *sortBy == "stored" || *top > 0 {
+		sort.Stable(ByStored(nodes))
+	}
+	if placementStopped {
+		fmt.Printf("PARTIAL RESULTS, max-duration %s reached after storing %d of %d chunks\n\n",
+			*maxDuration, chunksStored, totalStored)
+	}
+	if placementConverged {
+		fmt.Printf("CONVERGED, shares of storage settled after storing %d of %d chunks\n\n",
+			chunksStored, totalStored)
+	}
+	printHeader(strings.Split(vaultHeader(), ",")...)
+	for i, n := range nodes {
+		if *top > 0 && i >= *top && i < len(nodes)-*top {
+			// only the extremes
+			continue
+		}
+		printVault(n, shares[n.Name])
+	}
+	// the remaining metrics expect vaults in name order
+	sort.Sort(ByNodeName(nodes))
+	// both measures of spacing for the same names, whichever one bestfit
+	// used to choose them, next to the same measures of uniform names
+	ideal := idealNodes(len(nodes))
+	for _, spacingBy := range spacingStrategies {
+		spacings := spacingsBy(nodes, spacingBy)
+		idealSpacings := spacingsBy(ideal, spacingBy)
+		printMetricWithIdeal("Standard deviation of "+spacingBy+" spacings", stats.StandardDeviation(spacings), stats.StandardDeviation(idealSpacings))
+		// a few huge gaps or many moderate ones can have the same deviation
+		gaps := []float64{}
+		for _, spacing := range spacings {
+			gaps = append(gaps, float64(spacing))
+		}
+		idealGaps := []float64{}
+		for _, spacing := range idealSpacings {
+			idealGaps = append(idealGaps, float64(spacing))
+		}
+		for _, p := range []float64{10, 50, 90} {
+			printMetricWithIdeal(fmt.Sprintf("%gth percentile of %s spacings", p, spacingBy), uint64(stats.Percentile(gaps, p)), uint64(stats.Percentile(idealGaps, p)))
+		}
+		printMetricWithIdeal("Largest "+spacingBy+" spacing", stats.MaxUint64(spacings), stats.MaxUint64(idealSpacings))
+	}
+	// inequality implied by the names alone, before any chunks are stored
+	expected := []float64{}
+	for _, n := range nodes {
+		expected = append(expected, n.ExpectedShare)
+	}
+	printMetricWithIdeal("Gini of expected shares", stats.Gini(expected), 0)
+	printMetricWithIdeal("Ratio of largest expected share to average", stats.Percentile(expected, 100)/stats.AverageFloat(expected), 1)
+	// where the problem regions of the namespace are
+	reportExtremeSpacings(nodes)
+	// vaults close together fail together
+	reportCoMembership(nodes, ideal)
+	// heavy vaults next to each other
+	reportAutocorrelation(nodes)
+	// close groups are decided by xor proximity rather than adjacent names
+	if len(nodes) > 1 {
+		nearest := []float64{}
+		for _, distance := range nearestXorDistances(nodes) {
+			nearest = append(nearest, float64(distance))
+		}
+		idealNearest := []float64{}
+		for _, distance := range nearestXorDistances(ideal) {
+			idealNearest = append(idealNearest, float64(distance))
+		}
+		printMetricWithIdeal("Smallest xor distance to nearest vault", uint64(stats.Percentile(nearest, 0)), uint64(stats.Percentile(idealNearest, 0)))
+		for _, p := range []float64{10, 50, 90} {
+			printMetricWithIdeal(fmt.Sprintf("%gth percentile of xor distance to nearest vault", p), uint64(stats.Percentile(nearest, p)), uint64(stats.Percentile(idealNearest, p)))
+		}
+		printMetricWithIdeal("Largest xor distance to nearest vault", uint64(stats.Percentile(nearest, 100)), uint64(stats.Percentile(idealNearest, 100)))
+	}
+	stored := getAllStored(nodes)
+	printMetric("Average "+storageUnits+" stored per vault", stats.AverageFloat(stored))
+	printMetricWithIdeal("Standard deviation of "+storageUnits+" stored per vault", stats.StandardDeviationFloat(stored), 0)
+	// headline fairness, eg one vault stores 6x the average
+	most := stats.Percentile(stored, 100)
+	least := stats.Percentile(stored, 0)
+	if least > 0 {
+		printMetricWithIdeal("Ratio of most loaded to least loaded vault", most/least, 1)
+	} else {
+		// any amount over nothing is infinite
+		printMetricWithIdeal("Ratio of most loaded to least loaded vault", "undefined, a vault stores nothing", 1)
+	}
+	printMetricWithIdeal("Ratio of most loaded vault to average", most/stats.AverageFloat(stored), 1)
+	printMetricWithIdeal("Ratio of least loaded vault to average", least/stats.AverageFloat(stored), 1)
+	// imbalance from the spacing of names, and what is left over from
+	// where chunks happened to land
+	expectedStored := []float64{}
+	beyondExpected := []float64{}
+	for _, n := range nodes {
+		expectedStored = append(expectedStored, n.ExpectedStored)
+		beyondExpected = append(beyondExpected, n.Stored-n.ExpectedStored)
+	}
+	printMetricWithIdeal("Standard deviation of expected "+storageUnits+" stored per vault", stats.StandardDeviationFloat(expectedStored), 0)
+	printMetricWithIdeal("Standard deviation of "+storageUnits+" stored beyond expected", stats.StandardDeviationFloat(beyondExpected), 0)
+	// how well the space around a vault predicts its load
+	shareList := []float64{}
+	for _, share := range getKeyspaceShares(nodes) {
+		shareList = append(shareList, share)
+	}
+	correlation, err := stats.Correlation(shareList, stored)
+	if err != nil {
+		return err
+	}
+	printMetric("Correlation of "+storageUnits+" stored with keyspace share", correlation)
+	for s, spacingBy := range spacingStrategies {
+		gaps := []float64{}
+		for _, n := range nodes {
+			gaps = append(gaps, float64(n.Gaps[s]))
+		}
+		correlation, err = stats.Correlation(gaps, stored)
+		if err != nil {
+			return err
+		}
+		printMetric("Correlation of "+storageUnits+" stored with "+spacingBy+" gap", correlation)
+	}
+	if sampleScale > 0 {
+		// remove the variance added by sampling
+		deviation := stats.StandardDeviationFloat(stored)
+		noise := 0.0
+		for _, n := range nodes {
+			noise += math.Pow(samplingError(n.Stored), 2)
+		}
+		noise = noise / float64(len(nodes))
+		corrected := math.Sqrt(math.Max(0, deviation*deviation-noise))
+		printMetric("Standard deviation of "+storageUnits+" stored per vault excluding sampling noise", corrected)
+	}
+	var copies uint64 = 0
+	for _, n := range nodes {
+		copies += n.Chunks
+	}
+	printMetric("Total chunk copies held", copies)
+	// metadata load, which may concentrate differently from chunks
+	if *chunksPerFile > 0 {
+		metadata := []float64{}
+		for _, n := range nodes {
+			metadata = append(metadata, n.MetadataStored)
+		}
+		printMetric("Average metadata "+storageUnits+" stored per vault", stats.AverageFloat(metadata))
+		printMetric("Standard deviation of metadata "+storageUnits+" stored per vault", stats.StandardDeviationFloat(metadata))
+		printMetric("Ratio of most loaded vault to average for metadata", stats.Percentile(metadata, 100)/stats.AverageFloat(metadata))
+	}
+	// communication load, which may concentrate differently from storage
+	if consensusModel != "none" {
+		messages := []float64{}
+		for _, n := range nodes {
+			messages = append(messages, float64(n.Messages))
+		}
+		printMetric("Consensus messages sent", consensusMessages)
+		printMetric("Average consensus messages per vault", stats.AverageFloat(messages))
+		printMetric("Standard deviation of consensus messages per vault", stats.StandardDeviationFloat(messages))
+		printMetric("Ratio of most messages to average", stats.Percentile(messages, 100)/stats.AverageFloat(messages))
+	}
+	// cost of storing and reward for holding as the network fills
+	if vaultCapacity > 0 {
+		reportEconomics(nodes)
+	}
+	// how much of each vault's storage is never fetched
+	if getPhaseRequests > 0 {
+		reportTemperature(nodes)
+	}
+	// storage pressure moved to the archive tier
+	if archiveNodes > 0 {
+		reportArchive(nodes)
+	}
+	// responsibility of each role
+	if elderCount > 0 {
+		reportElders(nodes)
+	}
+	// closed-form expectations to compare with the simulated values
+	if namingStrategy == "uniform" || namingStrategy == "random" {
+		reportExpectations(len(nodes))
+	}
+	// survivorship, ie how long the remaining vaults have been in the network
+	printMetric("Average age of vaults (joins since joining)", stats.Average(getAllAges(nodes)))
+	// regional imbalance
+	reportSubsections(nodes)
+	// balance within each section
+	if sectionPrefixBits > 0 {
+		reportSections(nodes)
+	}
+	// collisions
+	printMetric("Vault name collisions", vaultNameCollisions)
+	printMetric("Chunk name collisions", chunkNameCollisions)
+	printMetric("Partial results", placementStopped)
+	if *verify > 0 {
+		printMetric("Placements verified by a full sort", placementsVerified)
+		printMetric("Placements differing from a full sort", placementMismatches)
+	}
+	if *convergeWindow > 0 {
+		printMetric("Converged", placementConverged)
+		printMetric("Chunks stored", chunksStored)
+	}
+	// localized storage pressure
+	if hotspotBits > 0 {
+		reportHotspot(nodes)
+	}
+	// cost of relocation between sections
+	if crossSectionRelocations > 0 {
+		reportCrossSectionRelocations()
+	}
+	// how often vaults relocate as they age
+	if relocationSchedule == "ageing" {
+		reportAgeing()
+	}
+	// data moved by each relocation after storing
+	if len(relocationMoves) > 0 {
+		reportRelocationMoves()
+	}
+	// skew from data stored while the network was small
+	if chunksStoredWhileGrowing > 0 {
+		reportGrowth(nodes)
+	}
+	// cost of restarts under the restart policy
+	if restarts > 0 {
+		printMetric("Restarts with "+*restartPolicy+" names", restarts)
+		printMetric("Data dropped by restarts ("+storageUnits+")", restartDropped)
+	}
+	// periodic refresh
+	if refreshes > 0 {
+		reportRefresh(nodes)
+	}
+	// read-repair
+	if churnAfterStoring > 0 {
+		reportReadRepair()
+	}
+	// responsibility moved by churn
+	if len(groupChangesPerEvent) > 0 {
+		reportGroupChanges()
+	}
+	if *outdir != "" {
+		err = writeResults(*outdir, nodes, shares)
+		if err != nil {
+			return err
+		}
+	}
+	if *tidy != "" {
+		err = writeTidy(*tidy, nodes, shares)
+		if err != nil {
+			return err
+		}
+	}
+	if *sqlitePath != "" {
+		err = appendToDatabase(*sqlitePath, nodes, shares)
+		if err != nil {
+			return err
+		}
+	}
+	if parquetVaults != nil {
+		err = parquetVaults.WriteVaults(nodes, shares, seed)
+		if err != nil {
+			return err
+		}
+		err = closeParquetVaults()
+		if err != nil {
+			return err
+		}
+	}
+	if placementMismatches > 0 {
+		return errors.New(strconv.Itoa(placementMismatches) + " placements differ from a full sort, the first for chunk " + nameStr(firstMismatchChunk))
+	}
+	return nil
+}
+
+func checkParameters() error {
+	// everything that can be wrong with the parameters, checked before
+	// anything runs
+	if !isValidStrategy(namingStrategy, namingStrategies) {
+		return ParameterError("Invalid naming strategy " + namingStrategy)
+	}
+	if !isValidStrategy(spacingStrategy, spacingStrategies) {
+		return ParameterError("Invalid spacing strategy " + spacingStrategy)
+	}
+	if !isValidStrategy(placementBackend, placementBackends) {
+		return ParameterError("Invalid placement backend " + placementBackend)
+	}
+	if storageUnits != "chunks" && storageUnits != "megabytes" {
+		return ParameterError("Invalid storage units " + storageUnits)
+	}
+	if growthModel != "none" && growthModel != "exponential" {
+		return ParameterError("Invalid growth model " + growthModel)
+	}
+	if growthModel == "exponential" && *chunksFile != "" {
+		return ParameterError("Growth stores generated chunk names, not a chunks file")
+	}
+	if relocationSchedule != "rate" && relocationSchedule != "ageing" {
+		return ParameterError("Invalid relocation schedule " + relocationSchedule)
+	}
+	if consensusModel != "none" && consensusModel != "alltoall" && consensusModel != "leader" {
+		return ParameterError("Invalid consensus model " + consensusModel)
+	}
+	if archiveNodes < 0 || archiveAfterChunks < 0 || archiveCopies < 1 {
+		return ParameterError("archiveNodes and archiveAfterChunks can't be negative and archiveCopies must be positive")
+	}
+	if getPhaseRequests < 0 || getZipfExponent <= 1 {
+		return ParameterError("getPhaseRequests can't be negative and getZipfExponent must be more than 1")
+	}
+	if vaultCapacity < 0 || baseStoreCost <= 0 || economicsPoints < 1 {
+		return ParameterError("vaultCapacity can't be negative and baseStoreCost and economicsPoints must be positive")
+	}
+	if archiveNodes > 0 && (churnAfterStoring > 0 || crossSectionRelocations > 0) {
+		return ParameterError("The archive tier is not modelled with churn after storing")
+	}
+	if !isValidStrategy(departureModel, departureModels) {
+		return ParameterError("Invalid departure model " + departureModel)
+	}
+	if *hotspot != "" {
+		_, bits, fraction, err := parseHotspot(*hotspot)
+		if err != nil {
+			return err
+		}
+		// a long prefix leaves few names in the hotspot, and once most are
+		// used new hotspot names almost always collide
+		if fraction*float64(totalStored) > math.Ldexp(1, int(64-bits))/2 {
+			return ParameterError("The hotspot prefix leaves too few names for the hotspot chunks, use a shorter prefix or a smaller fraction")
+		}
+	}
+	if totalNodes < 1 || totalStored < 0 || groupSize < 1 || bestFitDivisor < 2 {
+		return ParameterError("totalNodes and groupSize must be positive, totalStored can't be negative and bestFitDivisor must be at least 2")
+	}
+	if totalNodes-elderCount<<sectionPrefixBits < groupSize {
+		// elders don't store chunks, so every chunk would have too few copies
+		return ParameterError("groupSize can't be more than the vaults that aren't elders")
+	}
+	if quietestDepth < 1 || quietestDepth > maxQuietestDepth {
+		return ParameterError("quietestDepth must be from 1 to " + strconv.Itoa(int(maxQuietestDepth)))
+	}
+	if *sqlitePath != "" && appendToDatabase == nil {
+		return ParameterError("SQLite output isn't available in this build")
+	}
+	if *parquetPath != "" && newParquetVaults == nil {
+		return ParameterError("Parquet output isn't available in this build")
+	}
+	if *parquetPath != "" && (*splitThreshold > 0 || *shrink || *uploadChurn ||
+		*routingTrials > 0 || *failureTrials > 0 || *lossTrials > 0 ||
+		*handoff > 0 || *repairSweep > 0 || *halfLife > 0 || *study != "" ||
+		*tune || *paramsStdin || flag.NArg() > 0) {
+		return ParameterError("Parquet output is only for single runs, sweep and matrix")
+	}
+	if *verify < 0 || *verify > 1 {
+		return ParameterError("verify must be a fraction from 0 to 1")
+	}
+	if elderCount < 0 {
+		return ParameterError("elderCount can't be negative")
+	}
+	if elderCount > 0 && growthModel != "none" {
+		return ParameterError("Elders are chosen once the network has formed, so can't be used with growth")
+	}
+	if crossSectionRelocations > 0 && sectionPrefixBits == 0 {
+		return ParameterError("Cross-section relocation needs more than one section")
+	}
+	if subsectionDepth < 1 || subsectionDepth > maxSubsectionDepth {
+		return ParameterError("subsection-depth must be from 1 to " + strconv.Itoa(int(maxSubsectionDepth)))
+	}
+	if relocationRate < 0 {
+		return ParameterError("relocation-rate can't be negative")
+	}
+	if *convergeWindow < 0 || *convergeThreshold < 0 {
+		return ParameterError("converge-window and converge-threshold can't be negative")
+	}
+	if *splitThreshold < 0 {
+		return ParameterError("split-threshold can't be negative")
+	}
+	if *mergeThreshold != 0 && (*splitThreshold == 0 || *mergeThreshold < 0) {
+		return ParameterError("merge-threshold must be positive and needs split-threshold")
+	}
+	if *mergeThreshold > *splitThreshold {
+		// a merged section could split again straight away
+		return ParameterError("merge-threshold can't be more than split-threshold")
+	}
+	if *plotScript && *outdir == "" {
+		return ParameterError("plot-script needs outdir")
+	}
+	if *format != "csv" && *format != "tsv" && *format != "markdown" {
+		return ParameterError("Invalid format " + *format)
+	}
+	if *decimals < -1 {
+		return ParameterError("decimals can't be less than -1")
+	}
+	if *nameFormat != "hex" && *nameFormat != "base32" && *nameFormat != "binary" && *nameFormat != "xorname" {
+		return ParameterError("Invalid name-format " + *nameFormat)
+	}
+	if *restartPolicy != "same" && *restartPolicy != "new" {
+		return ParameterError("Invalid restart-policy " + *restartPolicy)
+	}
+	if *relocationRetention != "keep" && *relocationRetention != "drop" {
+		return ParameterError("Invalid relocation-retention " + *relocationRetention)
+	}
+	if *sortBy != "name" && *sortBy != "stored" {
+		return ParameterError("Invalid sort-by " + *sortBy)
+	}
+	if *study != "" && *study != "groupsize" && *study != "networksize" && *study != "departure" {
+		return ParameterError("Invalid study " + *study)
+	}
+	return nil
+}
+
+func simulatedNodes(ctx context.Context) ([]Node, error) {
+	// the network from a trace or from a new simulation
+	if *replay != "" {
+		nodes, err := replayTrace(*replay)
+		if err == nil && len(nodes) == 0 {
+			err = ParameterError("No vaults left after replaying " + *replay)
+		}
+		fmt.Println()
+		return nodes, err
+	}
+	return runSimulation(ctx)
+}
+
+func runSimulation(ctx context.Context) ([]Node, error) {
+	// create nodes
+	var nodes []Node
+	var err error
+	relocations := 0
+	// chunks are kept for read-repair
+	keepChunks := churnAfterStoring > 0 || crossSectionRelocations > 0 || archiveNodes > 0 || getPhaseRequests > 0
+	var chunks []Chunk
+	var holdings map[uint64][]int
+	placed := totalStored
+	if *sample > 0 {
+		placed = *sample
+	}
+	economicsInterval = placed / economicsPoints
+	growing := growthModel == "exponential" && *namesFile == ""
+	// holders can only change after placement if chunks are kept, otherwise
+	// each chunk is written as it is placed rather than being kept
+	if *assignments != "" && !keepChunks && !growing {
+		f, err := os.Create(*assignments)
+		if err != nil {
+			return nil, errors.New("Cannot create assignments: " + err.Error())
+		}
+		defer f.Close()
+		assignmentsWriter = bufio.NewWriter(f)
+		defer func() {
+			assignmentsWriter.Flush()
+			assignmentsWriter = nil
+		}()
+		fmt.Fprintln(assignmentsWriter, assignmentsHeader)
+	}
+	if *namesFile != "" {
+		nodes, err = loadNodes(*namesFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 0 {
+			return nil, ParameterError("No vault names in " + *namesFile)
+		}
+	} else if growing {
+		// chunks are stored as the network grows
+		nodes, chunks, holdings, relocations, err = growNetwork(ctx, placed)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		nodes, relocations = createNodes()
+	}
+	printParam("relocations", relocations)
+	fmt.Println()
+	if elderCount > 0 {
+		assignElders(nodes)
+	}
+	if *batchSize > 0 && !keepChunks && chunkNamesInput == nil && !growing {
+		err = storeChunksInBatches(ctx, nodes, placed, *batchSize)
+	} else if !growing {
+		chunks, holdings, err = storeChunks(ctx, nodes, placed, keepChunks)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// cold chunks leave their close groups
+	if archiveNodes > 0 {
+		archiveColdChunks(nodes, chunks)
+	}
+	err = checkChunkCopies(nodes)
+	if err != nil {
+		return nil, err
+	}
+	// relocations between sections
+	for i := 0; i < crossSectionRelocations; i++ {
+		relocateToOtherSection(nodes, chunks, holdings)
+	}
+	// churn after storing, with read-repair
+	groupFirstChanged = make([]int, len(chunks))
+	for i := 0; i < churnAfterStoring; i++ {
+		if ctx.Err() != nil {
+			if timedOut(ctx) {
+				break
+			}
+			return nil, ctx.Err()
+		}
+		event := i + 1
+		index := departingNodeIndex(nodes)
+		left := nodes[index].Name
+		groupChangesPerEvent = append(groupChangesPerEvent, float64(markGroupChanges(nodes, chunks, nodes[index], event)))
+		nodes = departWithChunks(nodes, index, chunks, holdings)
+		nodes = addNewNode(nodes)
+		joined := nodes[len(nodes)-1].Name
+		groupChangesPerEvent = append(groupChangesPerEvent, float64(markGroupChanges(nodes, chunks, nodes[len(nodes)-1], event)))
+		logChurnEvent("join", joined, "", len(nodes))
+		// the leave and the join may each trigger relocations
+		relocate := relocateMarkingGroupChanges(chunks, event, func(nodes []Node, index int) []Node {
+			return relocateWithChunks(nodes, index, chunks, holdings)
+		})
+		nodes, _ = relocateForEvent(nodes, left, relocate)
+		nodes, _ = relocateForEvent(nodes, joined, relocate)
+		for j := countForRate(restartRate); j > 0; j-- {
+			nodes = restartNode(nodes, chunks, holdings)
+		}
+		for j := 0; j < getRate; j++ {
+			nodes = getWithReadRepair(nodes, chunks, holdings)
+		}
+		// elders are the oldest vaults so may change with every event
+		if elderCount > 0 {
+			assignElders(nodes)
+		}
+		underReplicatedAfterEvent = append(underReplicatedAfterEvent, countUnderReplicated(chunks))
+		if refreshInterval > 0 && (i+1)%refreshInterval == 0 {
+			refreshChunks(nodes, chunks, holdings)
+		}
+	}
+	// how often each chunk is fetched
+	if getPhaseRequests > 0 {
+		measureTemperature(nodes, chunks)
+	}
+	if keepChunks || growing {
+		assignedChunks = chunks
+	}
+	// scale a sample up to the full amount stored
+	if *sample > 0 {
+		sampleScale = float64(totalStored) / float64(*sample)
+		for i, _ := range nodes {
+			nodes[i].Stored *= sampleScale
+			nodes[i].HotspotStored *= sampleScale
+			nodes[i].PrimaryStored *= sampleScale
+			nodes[i].MetadataStored *= sampleScale
+			nodes[i].ColdStored *= sampleScale
+		}
+		for i, _ := range archive {
+			archive[i].Stored *= sampleScale
+		}
+		archivedFromCloseGroups *= sampleScale
+	}
+	return nodes, nil
+}
+
+func samplingError(stored float64) float64 {
+	// The standard error of scaled storage. A vault holding count sampled
+	// chunks has variance count * E[size^2], and count is about
+	// stored / sampleScale / E[size].
+	meanSize := 1.0
+	meanSquareSize := 1.0
+	if storageUnits == "megabytes" {
+		meanSize, meanSquareSize = chunkSizeMoments()
+	}
+	return math.Sqrt(sampleScale * stored * meanSquareSize / meanSize)
+}
+
+func loadNodes(filename string) ([]Node, error) {
+	names, err := readNames(filename)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{}
+	existing := map[uint64]bool{}
+	for _, name := range names {
+		// a name listed twice is the same vault
+		if existing[name] {
+			vaultNameCollisions += 1
+			continue
+		}
+		existing[name] = true
+		node := Node{
+			Name:   name,
+			Stored: 0,
+			Joined: joins,
+		}
+		nodes = append(nodes, node)
+		joins += 1
+		traceNodeEvent("node_added", name)
+		logChurnEvent("join", name, "", len(nodes))
+	}
+	return nodes, nil
+}
+
+func readNames(filename string) ([]uint64, error) {
+	// one hex name per line, ignoring blank lines and # comments
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("Cannot open names: " + err.Error())
+	}
+	defer f.Close()
+	names := []uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, err := parseName(line)
+		if err != nil {
+			return nil, ParameterError("Invalid name " + line + ": " + err.Error())
+		}
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read names: " + err.Error())
+	}
+	return names, nil
+}
+
+func parseName(s string) (uint64, error) {
+	// hex with an optional 0x prefix, where longer names such as 256 bit
+	// XorNames are truncated to their leading 64 bits
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	if len(s) > 16 {
+		s = s[0:16]
+	}
+	name, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	// short names are the leading bits, like the names printed by nameStr
+	return name << (4 * uint(16-len(s))), nil
+}
+
+func growNetwork(ctx context.Context, totalChunks int) ([]Node, []Chunk, map[uint64][]int, int, error) {
+	nodes := []Node{}
+	chunks := []Chunk{}
+	holdings := map[uint64][]int{}
+	relocations := 0
+	size := growthStartNodes
+	for step := 0; step < growthSteps; step++ {
+		joined := false
+		for len(nodes) < size && len(nodes) < totalNodes {
+			joined = true
+			nodes = addNewNode(nodes)
+			logChurnEvent("join", nodes[len(nodes)-1].Name, "", len(nodes))
+			relocate := func(nodes []Node, index int) []Node {
+				return relocateWithChunks(nodes, index, chunks, holdings)
+			}
+			r := 0
+			nodes, r = relocateForEvent(nodes, nodes[len(nodes)-1].Name, relocate)
+			relocations += r
+		}
+		// handover uses the same mechanism as refresh so is reported with it
+		if joined && len(chunks) > 0 {
+			refreshChunks(nodes, chunks, holdings)
+		}
+		stepChunks := totalChunks / growthSteps
+		if step == growthSteps-1 {
+			stepChunks = totalChunks - stepChunks*(growthSteps-1)
+		}
+		stepStored, stepHoldings, err := storeChunks(ctx, nodes, stepChunks, true)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		if len(nodes) < totalNodes {
+			chunksStoredWhileGrowing += len(stepStored)
+		}
+		for name, indexes := range stepHoldings {
+			for _, c := range indexes {
+				holdings[name] = append(holdings[name], c+len(chunks))
+			}
+		}
+		chunks = append(chunks, stepStored...)
+		size *= 2
+	}
+	return nodes, chunks, holdings, relocations, nil
+}
+
+func reportGrowth(nodes []Node) {
+	printMetric("Fraction of chunks stored before the network reached totalNodes", float64(chunksStoredWhileGrowing)/float64(chunksStored))
+	// early vaults may still hold more of the data stored while the
+	// network was small
+	sorted := append([]Node{}, nodes...)
+	sort.Sort(ByJoined(sorted))
+	fmt.Println()
+	printHeader("join order (tenths)", "average "+storageUnits+" stored")
+	for tenth := 0; tenth < 10; tenth++ {
+		from := tenth * len(sorted) / 10
+		to := (tenth + 1) * len(sorted) / 10
+		stored := []float64{}
+		for _, n := range sorted[from:to] {
+			stored = append(stored, n.Stored)
+		}
+		if len(stored) > 0 {
+			printRow(strconv.Itoa(tenth+1), formatFloat(stats.AverageFloat(stored)))
+		}
+	}
+}
+
+func startRun(seed int64) {
+	// Reseeds the random numbers and clears the counters of any previous
+	// run, so runs in one process, eg a sweep, only depend on their seed.
+	rng.Seed(seed)
+	verifyRng.Seed(seed)
+	joins = 0
+	vaultNameCollisions, chunkNameCollisions = 0, 0
+	nameIndex = []uint64{}
+	quietestVaults = []int{}
+	repairTraffic, chunksRepaired = 0, 0
+	refreshes, refreshConfirmations = 0, 0
+	chunksRehomed, restarts, restartDropped, chunksLost = 0, 0, 0, 0
+	underReplicatedAfterEvent = []int{}
+	groupChangesPerEvent = []float64{}
+	groupFirstChanged = []int{}
+	relocationsByAge = map[int]int{}
+	ageingEvents = 0
+	consensusMessages = 0
+	archive, chunksArchived, archivedFromCloseGroups = nil, 0, 0
+	capacityUsed, uploadCost = 0, 0
+	economicsInterval, economics = 0, []EconomicsPoint{}
+	relocationDropped, relocationFetched = 0, 0
+	relocationMoves = []RelocationMove{}
+	placementsVerified, placementMismatches, firstMismatchChunk = 0, 0, 0
+	sampleScale = 0
+	chunksStored, chunksStoredWhileGrowing = 0, 0
+	placementStopped, placementConverged = false, false
+	convergedShares = map[uint64]float64{}
+	assignedChunks = nil
+}
+
+func createNodes() ([]Node, int) {
+	nodes := []Node{}
+	relocations := 0
+	for i := 0; i < totalNodes; i++ {
+		nodes = addNewNode(nodes)
+		logChurnEvent("join", nodes[len(nodes)-1].Name, "", len(nodes))
+		// each join may trigger relocations
+		r := 0
+		nodes, r = relocateForEvent(nodes, nodes[len(nodes)-1].Name, relocateNode)
+		relocations += r
+	}
+	return nodes, relocations
+}
+
+func storeChunks(ctx context.Context, nodes []Node, totalChunks int, keepChunks bool) ([]Chunk, map[uint64][]int, error) {
+	if chunkNamesInput != nil {
+		totalChunks = len(chunkNamesInput)
+	}
+	chunkNames := make(map[uint64]bool, totalChunks)
+	// chunks and the chunk indexes held by each vault, sized up front since
+	// growing them is most of the allocation when chunks are kept
+	chunks := []Chunk{}
+	holdings := map[uint64][]int{}
+	if keepChunks && len(nodes) > 0 {
+		chunks = make([]Chunk, 0, totalChunks)
+		holdings = make(map[uint64][]int, len(nodes))
+		perVault := totalChunks*groupSize/len(nodes) + 1
+		for _, n := range nodes {
+			holdings[n.Name] = make([]int, 0, perVault)
+		}
+	}
+	convergedShares = map[uint64]float64{}
+	for i := 0; i < totalChunks; i++ {
+		if i%cancelCheckChunks == 0 && ctx.Err() != nil {
+			if timedOut(ctx) {
+				break
+			}
+			return nil, nil, ctx.Err()
+		}
+		var chunkName uint64
+		isHotspot := false
+		if chunkNamesInput != nil {
+			// the same name twice is the same chunk, so is only stored once
+			name := chunkNamesInput[i]
+			if chunkNames[name] {
+				chunkNameCollisions += 1
+				continue
+			}
+			chunkName = name
+		} else {
+			chunkName, isHotspot = newChunkName()
+			for attempts := 1; chunkNames[chunkName]; attempts++ {
+				if attempts == maxChunkNameAttempts {
+					return nil, nil, errors.New("No unused chunk name after " + strconv.Itoa(attempts) + " attempts, the hotspot is full")
+				}
+				chunkNameCollisions += 1
+				chunkName, isHotspot = newChunkName()
+			}
+		}
+		chunkNames[chunkName] = true
+		amount := getChunkAmount()
+		group := placeChunk(nodes, chunkName, amount, isHotspot)
+		placeDatamapForFile(nodes)
+		if keepChunks {
+			chunk := Chunk{
+				Name:    chunkName,
+				Amount:  amount,
+				Holders: make([]uint64, 0, len(group)),
+				Hotspot: isHotspot,
+			}
+			for _, n := range group {
+				chunk.Holders = append(chunk.Holders, n.Name)
+				holdings[n.Name] = append(holdings[n.Name], len(chunks))
+			}
+			chunks = append(chunks, chunk)
+		}
+		if *convergeWindow > 0 && (i+1)%*convergeWindow == 0 && sharesConverged(nodes) {
+			placementConverged = true
+			break
+		}
+	}
+	return chunks, holdings, nil
+}
+
+func sharesConverged(nodes []Node) bool {
+	// Compares each vault's share of everything stored with the last
+	// check. Vaults are found by name since placement reorders them.
+	total := 0.0
+	for _, n := range nodes {
+		total += n.Stored
+	}
+	if total == 0 {
+		// nothing stored yet has no shares to compare
+		convergedShares = nil
+		return false
+	}
+	converged := len(convergedShares) == len(nodes)
+	shares := make(map[uint64]float64, len(nodes))
+	for _, n := range nodes {
+		share := n.Stored / total
+		previous, ok := convergedShares[n.Name]
+		if !ok || math.Abs(share-previous) > *convergeThreshold*previous {
+			converged = false
+		}
+		shares[n.Name] = share
+	}
+	convergedShares = shares
+	return converged
+}
+
+func timedOut(ctx context.Context) bool {
+	// max-duration ends the run early with partial results, unlike an
+	// interruption which is an error
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		placementStopped = true
+		return true
+	}
+	return false
+}
+
+func storeChunksInBatches(ctx context.Context, nodes []Node, totalChunks int, size int) error {
+	// buffers are allocated once and reused for every batch
+	names := make([]uint64, size)
+	hotspots := make([]bool, size)
+	amounts := make([]float64, size)
+	var progressFile *os.File
+	if *progress != "" {
+		f, err := os.Create(*progress)
+		if err != nil {
+			return errors.New("Cannot create progress: " + err.Error())
+		}
+		defer f.Close()
+		progressFile = f
+		fmt.Fprintln(f, "chunks stored,average "+storageUnits+" stored,standard deviation,min,max")
+	}
+	convergedShares = map[uint64]float64{}
+	for stored := 0; stored < totalChunks; {
+		if ctx.Err() != nil {
+			if timedOut(ctx) {
+				break
+			}
+			return ctx.Err()
+		}
+		batch := size
+		if totalChunks-stored < batch {
+			batch = totalChunks - stored
+		}
+		for i := 0; i < batch; i++ {
+			names[i], hotspots[i] = newChunkName()
+			amounts[i] = getChunkAmount()
+		}
+		for i := 0; i < batch; i++ {
+			placeChunk(nodes, names[i], amounts[i], hotspots[i])
+			placeDatamapForFile(nodes)
+		}
+		stored += batch
+		// intermediate metrics, written straight to the file so they survive
+		// an interrupted run
+		if progressFile != nil {
+			writeProgress(progressFile, nodes, stored)
+		}
+		// shares are checked whenever a batch ends a window
+		window := *convergeWindow
+		if window > 0 && stored/window > (stored-batch)/window && sharesConverged(nodes) {
+			placementConverged = true
+			break
+		}
+	}
+	return nil
+}
+
+func writeProgress(f *os.File, nodes []Node, stored int) {
+	total := 0.0
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	for _, n := range nodes {
+		total += n.Stored
+		min = math.Min(min, n.Stored)
+		max = math.Max(max, n.Stored)
+	}
+	avg := total / float64(len(nodes))
+	totalDiffs := 0.0
+	for _, n := range nodes {
+		totalDiffs += (n.Stored - avg) * (n.Stored - avg)
+	}
+	deviation := math.Sqrt(totalDiffs / float64(len(nodes)-1))
+	fmt.Fprintf(f, "%d,%f,%f,%f,%f\n", stored, avg, deviation, min, max)
+}
+
+func checkChunkCopies(nodes []Node) error {
+	// every chunk is held by groupSize vaults, unless its section is too
+	// small to have that many, which is only checked without sections
+	var copies uint64 = 0
+	for _, n := range nodes {
+		copies += n.Chunks
+	}
+	if sectionPrefixBits > 0 {
+		return nil
+	}
+	if len(nodes)-elderCount < groupSize {
+		return errors.New("Fewer vaults than groupSize store chunks")
+	}
+	if copies != uint64(chunksStored-chunksLost-chunksArchived)*uint64(groupSize) {
+		return errors.New("Chunk copies held by vaults do not equal chunks stored x groupSize")
+	}
+	return nil
+}
+
+func writeAssignments(filename string, chunks []Chunk) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return errors.New("Cannot create assignments: " + err.Error())
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	fmt.Fprintln(w, assignmentsHeader)
+	for _, chunk := range chunks {
+		writeAssignment(w, chunk.Name, chunk.Amount, chunk.Holders)
+	}
+	return nil
+}
+
+func writeAssignment(w *bufio.Writer, chunkName uint64, amount float64, holders []uint64) {
+	// holders are separated by spaces, closest first when not yet changed
+	// by churn
+	fmt.Fprintf(w, "%s,%f,", nameStr(chunkName), amount)
+	for i, holder := range holders {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, nameStr(holder))
+	}
+	fmt.Fprintln(w)
+}
+
+func readChunkNames(r io.Reader) ([]uint64, error) {
+	// every name in the chunks input, skipping blank lines and # comments
+	names := []uint64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, err := parseName(line)
+		if err != nil {
+			return nil, ParameterError("Invalid chunk name " + line + ": " + err.Error())
+		}
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read chunks: " + err.Error())
+	}
+	return names, nil
+}
+
+func placeDatamapForFile(nodes []Node) {
+	// once a file's chunks are stored, its datamap is stored by the group
+	// closest to the datamap name
+	if *chunksPerFile <= 0 || chunksStored%*chunksPerFile != 0 {
+		return
+	}
+	amount := 1.0
+	if storageUnits == "megabytes" {
+		amount = float64(*chunksPerFile) * datamapEntryMegabytes
+	}
+	datamapName := rng.Uint64()
+	var group []Node
+	if elderCount > 0 {
+		group = eldersOfSection(nodes, datamapName)
+	} else {
+		group = closestNodes(nodes, datamapName, groupSize)
+	}
+	for j, _ := range group {
+		group[j].MetadataStored += amount
+	}
+}
+
+func archiveColdChunks(nodes []Node, chunks []Chunk) {
+	// chunks are in the order they were stored, so all but the newest
+	// archiveAfterChunks are cold
+	archive = []Node{}
+	for i := 0; i < archiveNodes; i++ {
+		archive = append(archive, Node{Name: rng.Uint64()})
+	}
+	indexes := map[uint64]int{}
+	for i, n := range nodes {
+		indexes[n.Name] = i
+	}
+	// a sample stands for totalStored chunks
+	fresh := archiveAfterChunks
+	if *sample > 0 {
+		fresh = archiveAfterChunks * *sample / totalStored
+	}
+	for c := 0; c < len(chunks)-fresh; c++ {
+		amount := chunks[c].Amount
+		for h, holder := range chunks[c].Holders {
+			n := &nodes[indexes[holder]]
+			n.Stored -= amount
+			n.Chunks -= 1
+			// holders are in the order of the group, closest first
+			if h == 0 {
+				n.PrimaryStored -= amount
+			}
+			if chunks[c].Hotspot {
+				n.HotspotStored -= amount
+			}
+			archivedFromCloseGroups += amount
+		}
+		// archive vaults aren't in sections
+		xorSorter.Nodes = archive
+		xorSorter.Target = chunks[c].Name
+		sort.Sort(xorSorter)
+		chunks[c].Holders = []uint64{}
+		for j := 0; j < archiveCopies && j < len(archive); j++ {
+			archive[j].Stored += amount
+			archive[j].Chunks += 1
+			chunks[c].Holders = append(chunks[c].Holders, archive[j].Name)
+		}
+		chunksArchived += 1
+	}
+}
+
+func reportArchive(nodes []Node) {
+	fmt.Println()
+	printHeader("tier", "vaults", "total "+storageUnits+" stored", "average "+storageUnits+" stored", "max "+storageUnits+" stored")
+	closeGroups := getAllStored(nodes)
+	archived := getAllStored(archive)
+	printRow("close group", strconv.Itoa(len(closeGroups)), formatFloat(stats.SumFloat(closeGroups)), formatFloat(stats.AverageFloat(closeGroups)), formatFloat(stats.Percentile(closeGroups, 100)))
+	printRow("archive", strconv.Itoa(len(archived)), formatFloat(stats.SumFloat(archived)), formatFloat(stats.AverageFloat(archived)), formatFloat(stats.Percentile(archived, 100)))
+	printMetric("Chunks archived", chunksArchived)
+	remaining := stats.SumFloat(closeGroups)
+	printMetric("Fraction of close group storage moved to the archive tier", archivedFromCloseGroups/(archivedFromCloseGroups+remaining))
+}
+
+func assignElders(nodes []Node) {
+	// the elderCount oldest vaults in each section are its elders
+	sorted := append([]Node{}, nodes...)
+	sort.Sort(ByJoined(sorted))
+	elders := map[uint64]bool{}
+	perSection := map[uint64]int{}
+	for _, n := range sorted {
+		section := n.Name >> (64 - sectionPrefixBits)
+		if perSection[section] < elderCount {
+			elders[n.Name] = true
+			perSection[section] += 1
+		}
+	}
+	for i, _ := range nodes {
+		nodes[i].Elder = elders[nodes[i].Name]
+	}
+}
+
+func eldersOfSection(nodes []Node, name uint64) []Node {
+	// Returns the elders of the section of name. The returned slice shares
+	// storage with nodes, which are reordered so the elders come first.
+	elders := 0
+	for j, _ := range nodes {
+		if nodes[j].Elder && sameSection(nodes[j].Name, name) {
+			nodes[elders], nodes[j] = nodes[j], nodes[elders]
+			elders += 1
+		}
+	}
+	return nodes[0:elders]
+}
+
+func placeChunk(nodes []Node, chunkName uint64, amount float64, isHotspot bool) []Node {
+	// find nodes that store this chunk
+	group := closestNodes(nodes, chunkName, groupSize)
+	if *verify > 0 && verifyRng.Float64() < *verify {
+		verifyPlacement(nodes, chunkName, group)
+	}
+	if consensusModel != "none" {
+		countVotes(group)
+	}
+	// add chunk to the closest group nodes
+	for j, _ := range group {
+		group[j].Stored += amount
+		group[j].Chunks += 1
+		if isHotspot {
+			group[j].HotspotStored += amount
+		}
+		// nodes are sorted so the first is the closest
+		if j == 0 {
+			group[j].PrimaryStored += amount
+		}
+	}
+	chunksStored += 1
+	if vaultCapacity > 0 {
+		chargeStoreCost(nodes, group, amount)
+	}
+	if assignmentsWriter != nil {
+		holders := make([]uint64, 0, len(group))
+		for _, n := range group {
+			holders = append(holders, n.Name)
+		}
+		writeAssignment(assignmentsWriter, chunkName, amount, holders)
+	}
+	if traceEncoder != nil {
+		groupNames := []string{}
+		for _, n := range group {
+			groupNames = append(groupNames, hexName(n.Name))
+		}
+		traceEvent(TraceEvent{
+			Event:  "chunk_stored",
+			Name:   hexName(chunkName),
+			Amount: amount,
+			Group:  groupNames,
+		})
+	}
+	return group
+}
+
+func verifyPlacement(nodes []Node, chunkName uint64, group []Node) {
+	// compares the group with the closest vaults from sorting a copy of
+	// every vault that can hold the chunk
+	candidates := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		if samePrefix(n.Name, chunkName, sectionPrefixBits) && !n.Elder {
+			candidates = append(candidates, n)
+		}
+	}
+	sort.Sort(&ByXorDistance{Nodes: candidates, Target: chunkName})
+	if len(candidates) > groupSize {
+		candidates = candidates[0:groupSize]
+	}
+	placementsVerified += 1
+	matches := len(candidates) == len(group)
+	for j := 0; matches && j < len(group); j++ {
+		matches = candidates[j].Name == group[j].Name
+	}
+	if !matches {
+		if placementMismatches == 0 {
+			firstMismatchChunk = chunkName
+		}
+		placementMismatches += 1
+	}
+}
+
+func chargeStoreCost(nodes []Node, group []Node, amount float64) {
+	// a sample stands for totalStored chunks
+	scale := 1.0
+	if *sample > 0 {
+		scale = float64(totalStored) / float64(*sample)
+	}
+	// the network is never treated as completely full, which would make
+	// the store cost infinite
+	capacity := vaultCapacity * float64(len(nodes))
+	spare := math.Max(0.01, 1-capacityUsed/capacity)
+	cost := baseStoreCost * amount * scale / spare
+	uploadCost += cost
+	for j, _ := range group {
+		group[j].Reward += cost / float64(len(group))
+	}
+	capacityUsed += amount * scale * float64(len(group))
+	if economicsInterval > 0 && chunksStored%economicsInterval == 0 {
+		rewards := []float64{}
+		for _, n := range nodes {
+			rewards = append(rewards, n.Reward)
+		}
+		economics = append(economics, EconomicsPoint{
+			Chunks:        chunksStored,
+			Spare:         spare,
+			StoreCost:     baseStoreCost / spare,
+			UploadCost:    uploadCost,
+			AverageReward: stats.AverageFloat(rewards),
+			Deviation:     stats.StandardDeviationFloat(getAllStored(nodes)) * scale,
+		})
+	}
+}
+
+func reportEconomics(nodes []Node) {
+	fmt.Println()
+	printHeader("chunks stored", "spare capacity", "store cost per "+storageUnits, "total upload cost", "average reward per vault", "standard deviation of "+storageUnits+" stored")
+	for _, p := range economics {
+		printRow(strconv.Itoa(p.Chunks), formatFloat(p.Spare), formatFloat(p.StoreCost), formatFloat(p.UploadCost), formatFloat(p.AverageReward), formatFloat(p.Deviation))
+	}
+	rewards := []float64{}
+	for _, n := range nodes {
+		rewards = append(rewards, n.Reward)
+	}
+	printMetric("Total upload cost", uploadCost)
+	printMetric("Ratio of most rewarded vault to average", stats.Percentile(rewards, 100)/stats.AverageFloat(rewards))
+	printMetric("Gini of farming reward", stats.Gini(rewards))
+}
+
+func replayTrace(filename string) ([]Node, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("Cannot open trace: " + err.Error())
+	}
+	defer f.Close()
+	nodes := []Node{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e TraceEvent
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, ParameterError("Invalid trace event: " + err.Error())
+		}
+		name, err := strconv.ParseUint(e.Name, 16, 64)
+		if err != nil {
+			return nil, ParameterError("Invalid name in trace: " + e.Name)
+		}
+		// the recorded group is ignored, the chunk is placed again using
+		// the current parameters
+		if e.Event == "node_added" {
+			node := Node{
+				Name:   name,
+				Stored: 0,
+				Joined: joins,
+			}
+			nodes = append(nodes, node)
+			joins += 1
+			traceEvent(TraceEvent{Event: "node_added", Name: e.Name})
+		} else if e.Event == "node_removed" {
+			for i, node := range nodes {
+				if node.Name == name {
+					nodes = removeNode(nodes, i)
+					break
+				}
+			}
+		} else if e.Event == "chunk_stored" {
+			placeChunk(nodes, name, e.Amount, false)
+		} else {
+			return nil, ParameterError("Unknown trace event: " + e.Event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read trace: " + err.Error())
+	}
+	return nodes, nil
+}
+
+func addNewNode(nodes []Node) []Node {
+	// get current names in order
+	names := sortedNames(nodes)
+	// get name that suits the naming strategy, regenerating on collision
+	nodeName := nameForStrategy(names, len(nodes))
+	for nameIsIndexed(nodeName) {
+		vaultNameCollisions += 1
+		nodeName = nameForStrategy(names, len(nodes))
+	}
+	indexName(nodeName)
+	// the existing vaults agree on the join
+	countMembershipVotes(nodes, nodeName)
+	// add new node to nodes
+	node := Node{
+		Name:   nodeName,
+		Stored: 0,
+		Joined: joins,
+	}
+	nodes = append(nodes, node)
+	joins += 1
+	traceNodeEvent("node_added", nodeName)
+	return nodes
+}
+
+func removeNode(nodes []Node, index int) []Node {
+	name := nodes[index].Name
+	traceNodeEvent("node_removed", name)
+	nodes = append(nodes[0:index], nodes[index+1:]...)
+	unindexName(name)
+	// the remaining vaults agree on the leave
+	countMembershipVotes(nodes, name)
+	return nodes
+}
+
+func countMembershipVotes(nodes []Node, name uint64) {
+	// Membership changes are agreed by the group for the name. Callers rely
+	// on the order of nodes, so the group is found in a copy.
+	if consensusModel == "none" {
+		return
+	}
+	voters := append([]Node{}, nodes...)
+	var group []Node
+	if elderCount > 0 {
+		group = eldersOfSection(voters, name)
+	} else {
+		group = closestNodes(voters, name, groupSize)
+	}
+	countVotes(group)
+	for _, voter := range group {
+		for i, _ := range nodes {
+			if nodes[i].Name == voter.Name {
+				nodes[i].Messages = voter.Messages
+				break
+			}
+		}
+	}
+}
+
+func countVotes(group []Node) {
+	// adds the messages each member sends and receives while agreeing, with
+	// the closest member first
+	size := len(group)
+	for j, _ := range group {
+		if consensusModel == "alltoall" {
+			group[j].Messages += 2 * (size - 1)
+		} else if consensusModel == "leader" && j == 0 {
+			group[j].Messages += 2 * (size - 1)
+		} else if consensusModel == "leader" {
+			group[j].Messages += 2
+		}
+	}
+	if consensusModel == "alltoall" {
+		consensusMessages += size * (size - 1)
+	} else if consensusModel == "leader" {
+		consensusMessages += 2 * (size - 1)
+	}
+}
+
+func traceEvent(e TraceEvent) {
+	if traceEncoder == nil {
+		return
+	}
+	traceEncoder.Encode(e)
+}
+
+func traceNodeEvent(event string, name uint64) {
+	// names are only formatted when tracing, since joins and leaves are
+	// frequent
+	if traceEncoder == nil {
+		return
+	}
+	traceEvent(TraceEvent{Event: event, Name: hexName(name)})
+}
+
+func nameForStrategy(names []uint64, totalExisting int) uint64 {
+	var nodeName uint64
+	// generate the next node name
+	if namingStrategy == "uniform" {
+		progress := float64(totalExisting) / float64(totalNodes)
+		nodeName = uint64(float64(math.MaxUint64) * progress)
+	} else if namingStrategy == "random" {
+		nodeName = rng.Uint64()
+	} else if namingStrategy == "bestfit" {
+		nodeName = strategy.NameForBestFit(rng, names, bestFitDivisor, spacingFor(spacingStrategy))
+	} else if namingStrategy == "quietesthalf" {
+		name, err := strategy.NameForQuietestHalf(rng, indexedSubsectionVaults(), quietestDepth)
+		if err != nil {
+			// quietestDepth is checked before anything runs, so there are
+			// always subsections, but a random name is the same as depth 0
+			name = rng.Uint64()
+		}
+		nodeName = name
+	} else {
+		// naming strategies are checked before anything runs, so this is
+		// emptysubsection
+		nodeName = strategy.NameForEmptySubsection(rng, names)
+	}
+	return nodeName
+}
+
+func sortedNames(nodes []Node) []uint64 {
+	// Returns the names of nodes in order. The index is kept in step by
+	// addNewNode and removeNode, and is rebuilt for a network that was
+	// created or loaded some other way.
+	if len(nameIndex) != len(nodes) {
+		nameIndex = []uint64{}
+		for _, node := range nodes {
+			nameIndex = append(nameIndex, node.Name)
+		}
+		sort.Sort(ByName(nameIndex))
+		quietestVaults = []int{}
+	}
+	return nameIndex
+}
+
+func indexedSubsectionVaults() []int {
+	// returns the vaults in each subsection of the index at quietestDepth,
+	// counting them again if the depth has changed
+	if len(quietestVaults) != 1<<quietestDepth {
+		// an invalid depth leaves no subsections to count
+		quietestVaults, _ = strategy.CountSubsectionVaults(nameIndex, quietestDepth)
+	}
+	return quietestVaults
+}
+
+func nameIndexPosition(name uint64) int {
+	// the position of name in the index, or where it would be inserted
+	return sort.Search(len(nameIndex), func(i int) bool {
+		return nameIndex[i] >= name
+	})
+}
+
+func nameIsIndexed(name uint64) bool {
+	i := nameIndexPosition(name)
+	return i < len(nameIndex) && nameIndex[i] == name
+}
+
+func indexName(name uint64) {
+	i := nameIndexPosition(name)
+	nameIndex = append(nameIndex, 0)
+	copy(nameIndex[i+1:], nameIndex[i:])
+	nameIndex[i] = name
+	if len(quietestVaults) == 1<<quietestDepth {
+		quietestVaults[strategy.SubsectionOf(name, quietestDepth)] += 1
+	}
+}
+
+func unindexName(name uint64) {
+	if !nameIsIndexed(name) {
+		return
+	}
+	i := nameIndexPosition(name)
+	nameIndex = append(nameIndex[:i], nameIndex[i+1:]...)
+	if len(quietestVaults) == 1<<quietestDepth {
+		quietestVaults[strategy.SubsectionOf(name, quietestDepth)] -= 1
+	}
+}
+
+func nameIsTaken(name uint64, names []uint64) bool {
+	for _, existing := range names {
+		if existing == name {
+			return true
+		}
+	}
+	return false
+}
+
+func relocateForEvent(nodes []Node, eventName uint64, relocate func([]Node, int) []Node) ([]Node, int) {
+	// relocates vaults after the join or leave of eventName according to
+	// the relocation schedule, returning how many were relocated
+	if namingStrategy == "uniform" {
+		return nodes, 0
+	}
+	if relocationSchedule == "ageing" {
+		aged := ageVaults(nodes, eventName)
+		for _, name := range aged {
+			for i, _ := range nodes {
+				if nodes[i].Name == name {
+					nodes = relocate(nodes, i)
+					break
+				}
+			}
+		}
+		return nodes, len(aged)
+	}
+	r := relocationsForEvent()
+	for j := 0; j < r; j++ {
+		nodes = relocate(nodes, departingNodeIndex(nodes))
+	}
+	return nodes, r
+}
+
+func ageVaults(nodes []Node, eventName uint64) []uint64 {
+	// Every other vault in the section of the event gets older, and returns
+	// the names of those whose age has doubled since they were last
+	// relocated, ie is now a power of two.
+	ageingEvents += 1
+	aged := []uint64{}
+	for i, _ := range nodes {
+		if nodes[i].Name == eventName || !sameSection(nodes[i].Name, eventName) {
+			continue
+		}
+		nodes[i].Age += 1
+		if nodes[i].Age >= 2 && nodes[i].Age&(nodes[i].Age-1) == 0 {
+			aged = append(aged, nodes[i].Name)
+			relocationsByAge[nodes[i].Age] += 1
+		}
+	}
+	return aged
+}
+
+func relocateNode(nodes []Node, index int) []Node {
+	// the relocated vault leaves and rejoins with a new name, keeping its age
+	oldName := nodes[index].Name
+	age := nodes[index].Age
+	nodes = removeNode(nodes, index)
+	nodes = addNewNode(nodes)
+	nodes[len(nodes)-1].Age = age
+	newName := nodes[len(nodes)-1].Name
+	logRenameEvent("relocation", oldName, newName, len(nodes))
+	return nodes
+}
+
+func logChurnEvent(event string, name uint64, newName string, networkSize int) {
+	if churnLogWriter == nil {
+		return
+	}
+	e := ChurnEvent{
+		Time:        churnEventCount,
+		Event:       event,
+		Name:        nameStr(name),
+		NewName:     newName,
+		NetworkSize: networkSize,
+	}
+	churnEventCount += 1
+	if churnLogEncoder != nil {
+		churnLogEncoder.Encode(e)
+		return
+	}
+	fmt.Fprintf(churnLogWriter, "%d,%s,%s,%s,%d\n", e.Time, e.Event, e.Name, e.NewName, e.NetworkSize)
+}
+
+func logRenameEvent(event string, name uint64, newName uint64, networkSize int) {
+	// the new name is only formatted when there is a churn log
+	if churnLogWriter == nil {
+		return
+	}
+	logChurnEvent(event, name, nameStr(newName), networkSize)
+}
+
+func departingNodeIndex(nodes []Node) int {
+	if departureModel == "uniform" {
+		return rng.Intn(len(nodes))
+	} else {
+		// departure models are checked before anything runs, so this is
+		// young, where younger vaults have a higher weight so are more
+		// likely to leave
+		weights := make([]float64, len(nodes))
+		totalWeight := 0.0
+		for i, node := range nodes {
+			age := joins - node.Joined
+			weights[i] = 1 / float64(age+1)
+			totalWeight += weights[i]
+		}
+		r := rng.Float64() * totalWeight
+		for i, weight := range weights {
+			r -= weight
+			if r < 0 {
+				return i
+			}
+		}
+		return len(nodes) - 1
+	}
+}
+
+func getAllAges(nodes []Node) []uint64 {
+	ages := []uint64{}
+	for _, node := range nodes {
+		ages = append(ages, uint64(joins-node.Joined))
+	}
+	return ages
+}
+
+func analyze(ctx context.Context, args []string) error {
+	// compares a simulation with observed storage from a real network
+	analyzeFlags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	actual := analyzeFlags.String("actual", "", "csv of vault name,stored "+
+		"observed on a real or test network")
+	err := flagsFromEnvironment(analyzeFlags, "SAFE_SIM_ANALYZE_")
+	if err != nil {
+		return err
+	}
+	analyzeFlags.Parse(args)
+	if *actual == "" {
+		return ParameterError("analyze needs --actual snapshot.csv")
+	}
+	observed, err := readSnapshot(*actual)
+	if err != nil {
+		return err
+	}
+	nodes, err := runSimulation(ctx)
+	if err != nil {
+		return err
+	}
+	simulated := getAllStored(nodes)
+	printHeader("metric", "simulated", "observed")
+	printRow("vaults", strconv.Itoa(len(simulated)), strconv.Itoa(len(observed)))
+	metrics := []string{
+		"average",
+		"standard deviation",
+		"relative standard deviation",
+		"gini",
+		"min",
+		"p10",
+		"p50",
+		"p90",
+		"max",
+	}
+	for _, metric := range metrics {
+		s, err := storageMetric(metric, simulated)
+		if err != nil {
+			return err
+		}
+		o, err := storageMetric(metric, observed)
+		if err != nil {
+			return err
+		}
+		printRow(metric, formatFloat(s), formatFloat(o))
+	}
+	return nil
+}
+
+func query(ctx context.Context, args []string) error {
+	// prints the group responsible for a chunk once the network has formed
+	if len(args) != 1 {
+		return ParameterError("query needs a chunk name")
+	}
+	chunkName, err := parseName(args[0])
+	if err != nil {
+		return ParameterError("Invalid chunk name: " + err.Error())
+	}
+	nodes, err := simulatedNodes(ctx)
+	if err != nil {
+		return err
+	}
+	group := closestNodes(nodes, chunkName, groupSize)
+	fmt.Println("\nchunk " + nameStr(chunkName))
+	printHeader("vault name", "xor distance", storageUnits+" stored")
+	for _, node := range group {
+		printRow(nameStr(node.Name), nameStr(node.Name^chunkName), formatFloat(node.Stored))
+	}
+	return nil
+}
+
+func bench(ctx context.Context, args []string) error {
+	// measures how many chunks each placement backend places per second
+	benchFlags := flag.NewFlagSet("bench", flag.ExitOnError)
+	vaults := benchFlags.String("vaults", "100,1000,10000", "comma separated "+
+		"network sizes to place chunks in")
+	benchChunks := benchFlags.Int("chunks", 10000, "chunks placed in each "+
+		"network")
+	backend := benchFlags.String("backend", "", "placement backend to "+
+		"measure, "+strings.Join(placementBackends, " or ")+", default all")
+	err := flagsFromEnvironment(benchFlags, "SAFE_SIM_BENCH_")
+	if err != nil {
+		return err
+	}
+	benchFlags.Parse(args)
+	sizes := []int{}
+	for _, v := range strings.Split(*vaults, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || size < 1 {
+			return ParameterError("Invalid bench network size " + v)
+		}
+		sizes = append(sizes, size)
+	}
+	if *benchChunks < 1 {
+		return ParameterError("bench needs at least one chunk")
+	}
+	backends := placementBackends
+	if *backend != "" {
+		if !isValidStrategy(*backend, placementBackends) {
+			return ParameterError("Invalid placement backend " + *backend)
+		}
+		backends = []string{*backend}
+	}
+	initialNodes, initialBackend := totalNodes, placementBackend
+	defer func() {
+		totalNodes, placementBackend = initialNodes, initialBackend
+	}()
+	fmt.Println()
+	printHeader("backend", "vaults", "chunks", "seconds", "chunks per second")
+	for _, b := range backends {
+		placementBackend = b
+		for _, size := range sizes {
+			totalNodes = size
+			nodes, _ := createNodes()
+			start := time.Now()
+			_, _, err := storeChunks(ctx, nodes, *benchChunks, false)
+			if err != nil {
+				return err
+			}
+			seconds := time.Since(start).Seconds()
+			printRow(b, strconv.Itoa(size), strconv.Itoa(*benchChunks),
+				fmt.Sprintf("%f", seconds),
+				fmt.Sprintf("%.0f", float64(*benchChunks)/seconds))
+		}
+	}
+	return nil
+}
+
+func serve(ctx context.Context, seed int64, args []string) error {
+	// Runs submitted over http, and gRPC if it is served, share one queue.
+	serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := serveFlags.String("addr", "localhost:8080", "address to listen on")
+	grpcAddr := serveFlags.String("grpc-addr", "", "also serve the gRPC "+
+		"API of proto/simulation.proto on this address")
+	workers := serveFlags.Int("workers", runtime.NumCPU(), "runs at the "+
+		"same time")
+	queueSize := serveFlags.Int("queue", 100, "runs waiting for a worker "+
+		"before new runs are refused")
+	timeout := serveFlags.Duration("timeout", 10*time.Minute, "longest a "+
+		"run can take before it fails")
+	maxNodes := serveFlags.Int("max-nodes", 10000, "most vaults in a run, "+
+		"larger runs are refused")
+	maxStored := serveFlags.Int("max-stored", 10000000, "most chunks stored "+
+		"in a run, larger runs are refused")
+	err := flagsFromEnvironment(serveFlags, "SAFE_SIM_SERVE_")
+	if err != nil {
+		return err
+	}
+	serveFlags.Parse(args)
+	if *workers < 1 || *queueSize < 1 {
+		return ParameterError("serve needs at least one worker and queue place")
+	}
+	if *timeout <= 0 || *maxNodes < 1 || *maxStored < 1 {
+		return ParameterError("serve needs a positive timeout, max-nodes and max-stored")
+	}
+	if *grpcAddr != "" && serveGRPC == nil {
+		return ParameterError("gRPC isn't available in this build")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.New("Cannot find executable: " + err.Error())
+	}
+	jobs := &JobQueue{
+		queue:     make(chan *Job, *queueSize),
+		seed:      seed,
+		finished:  make(chan struct{}),
+		timeout:   *timeout,
+		maxNodes:  *maxNodes,
+		maxStored: *maxStored,
+	}
+	for i := 0; i < *workers; i++ {
+		go jobs.work(ctx, executable)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", func(w http.ResponseWriter, r *http.Request) {
+		job, err := jobs.Submit(func(params *RunParams) error {
+			return json.NewDecoder(r.Body).Decode(params)
+		})
+		if err == errQueueFull {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Location", "/runs/"+strconv.Itoa(job.ID))
+		writeJob(w, http.StatusAccepted, job)
+	})
+	mux.HandleFunc("GET /runs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		job, ok := jobs.Get(id)
+		if err != nil || !ok {
+			http.Error(w, "No such run", http.StatusNotFound)
+			return
+		}
+		writeJob(w, http.StatusOK, job)
+	})
+	server := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	// either server failing stops serve, otherwise both stop with ctx
+	errs := make(chan error, 2)
+	servers := 1
+	if *grpcAddr != "" {
+		servers += 1
+		go func() {
+			errs <- serveGRPC(ctx, *grpcAddr, jobs)
+		}()
+		fmt.Println("\nserving runs over gRPC on " + *grpcAddr)
+	}
+	go func() {
+		err := server.ListenAndServe()
+		if err != http.ErrServerClosed {
+			errs <- errors.New("Cannot serve: " + err.Error())
+			return
+		}
+		errs <- nil
+	}()
+	fmt.Println("\nserving runs on http://" + *addr + "/runs")
+	for i := 0; i < servers; i++ {
+		err = <-errs
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *JobQueue) work(ctx context.Context, executable string) {
+	// Simulations share global state, so each run is a separate process
+	// of this program using params-stdin.
+	for job := range q.queue {
+		q.mutex.Lock()
+		job.Status = "running"
+		params := job.Params
+		q.mutex.Unlock()
+		runCtx, cancel := context.WithTimeout(ctx, q.timeout)
+		result := runInProcess(runCtx, executable, params)
+		if runCtx.Err() == context.DeadlineExceeded {
+			result.Error = "Run took longer than " + q.timeout.String()
+		}
+		cancel()
+		q.mutex.Lock()
+		job.Result = &result
+		job.Status = "done"
+		if result.Error != "" {
+			job.Status = "failed"
+		}
+		close(q.finished)
+		q.finished = make(chan struct{})
+		q.mutex.Unlock()
+	}
+}
+
+// Submit queues a run, with decode setting the parameters it was given over
+// the defaults. Missing parameters are the defaults, with consecutive seeds.
+func (q *JobQueue) Submit(decode func(params *RunParams) error) (Job, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	params := defaultRunParams(q.seed + int64(len(q.jobs)))
+	err := decode(&params)
+	if err != nil {
+		return Job{}, ParameterError("Invalid parameters: " + err.Error())
+	}
+	if params.TotalNodes > q.maxNodes || params.TotalStored > q.maxStored {
+		return Job{}, ParameterError("Runs are limited to " + strconv.Itoa(q.maxNodes) +
+			" vaults and " + strconv.Itoa(q.maxStored) + " chunks stored")
+	}
+	job := &Job{ID: len(q.jobs) + 1, Status: "queued", Params: params}
+	select {
+	case q.queue <- job:
+	default:
+		return Job{}, errQueueFull
+	}
+	q.jobs = append(q.jobs, job)
+	return *job, nil
+}
+
+// Get returns a copy of the run with this id, or false if there isn't one.
+func (q *JobQueue) Get(id int) (Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if id < 1 || id > len(q.jobs) {
+		return Job{}, false
+	}
+	return *q.jobs[id-1], true
+}
+
+// Wait calls each with every run in ids as it finishes, or with every run
+// submitted so far when there are no ids, and returns once all have.
+func (q *JobQueue) Wait(ctx context.Context, ids []int, each func(job Job) error) error {
+	q.mutex.Lock()
+	pending := map[int]bool{}
+	for _, id := range ids {
+		if id < 1 || id > len(q.jobs) {
+			q.mutex.Unlock()
+			return ParameterError("No such run " + strconv.Itoa(id))
+		}
+		pending[id] = true
+	}
+	if len(ids) == 0 {
+		for i, _ := range q.jobs {
+			pending[i+1] = true
+		}
+	}
+	q.mutex.Unlock()
+	for len(pending) > 0 {
+		q.mutex.Lock()
+		finished := q.finished
+		done := []Job{}
+		for id, _ := range pending {
+			if q.jobs[id-1].Result != nil {
+				done = append(done, *q.jobs[id-1])
+				delete(pending, id)
+			}
+		}
+		q.mutex.Unlock()
+		sort.Slice(done, func(i, j int) bool { return done[i].ID < done[j].ID })
+		for _, job := range done {
+			err := each(job)
+			if err != nil {
+				return err
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func runInProcess(ctx context.Context, executable string, params RunParams) RunResult {
+	// runs one set of parameters in a new process of this program
+	input, _ := json.Marshal(params)
+	cmd := exec.CommandContext(ctx, executable, "--params-stdin")
+	cmd.Stdin = strings.NewReader(string(input) + "\n")
+	// stderr says why a run failed, eg a bad parameter or a panic
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	result := RunResult{Params: &params}
+	if err != nil {
+		result.Error = "Run failed: " + err.Error() + withStderr(stderr.String())
+		return result
+	}
+	err = json.Unmarshal(output, &result)
+	if err != nil {
+		result.Error = "Invalid run result: " + err.Error() + withStderr(stderr.String())
+	}
+	return result
+}
+
+func withStderr(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	return ": " + stderr
+}
+
+func writeJob(w http.ResponseWriter, status int, job Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
+func storageMetric(metric string, stored []float64) (float64, error) {
+	if metric == "average" {
+		return stats.AverageFloat(stored), nil
+	} else if metric == "standard deviation" {
+		return stats.StandardDeviationFloat(stored), nil
+	} else if metric == "relative standard deviation" {
+		// comparable between networks of different sizes
+		return stats.StandardDeviationFloat(stored) / stats.AverageFloat(stored), nil
+	} else if metric == "gini" {
+		return stats.Gini(stored), nil
+	} else if metric == "min" {
+		return stats.Percentile(stored, 0), nil
+	} else if metric == "p10" {
+		return stats.Percentile(stored, 10), nil
+	} else if metric == "p50" {
+		return stats.Percentile(stored, 50), nil
+	} else if metric == "p90" {
+		return stats.Percentile(stored, 90), nil
+	} else if metric == "max" {
+		return stats.Percentile(stored, 100), nil
+	}
+	return 0, errors.New("Invalid metric " + metric)
+}
+
+func readSnapshot(filename string) ([]float64, error) {
+	// lines of vault name,stored with an optional header line
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("Cannot open snapshot: " + err.Error())
+	}
+	defer f.Close()
+	stored := []float64{}
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) < 2 {
+			return nil, ParameterError("Invalid snapshot line: " + line)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			if i == 0 {
+				// header
+				continue
+			}
+			return nil, ParameterError("Invalid stored amount: " + line)
+		}
+		stored = append(stored, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read snapshot: " + err.Error())
+	}
+	// the metrics compared need at least one vault
+	if len(stored) == 0 {
+		return nil, ParameterError("No vaults in snapshot " + filename)
+	}
+	return stored, nil
+}
+
+func reportSweep(ctx context.Context, first int64, seeds int) error {
+	// finds pathological seeds, which can be rerun with the seed flag
+	thresholds := []float64{}
+	for _, v := range strings.Split(*dominance, ",") {
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || threshold <= 0 || threshold >= 100 {
+			return ParameterError("Invalid dominance percentage " + v)
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	fmt.Println()
+	printHeader("seed", "standard deviation of "+storageUnits+" stored", "gini", "max / average", "largest share of data")
+	deviations := []float64{}
+	largestShares := []float64{}
+	worst, best := first, first
+	for i := 0; i < seeds; i++ {
+		seed := first + int64(i)
+		startRun(seed)
+		nodes, _ := createNodes()
+		_, _, err := storeChunks(ctx, nodes, totalStored, false)
+		if err != nil {
+			return err
+		}
+		err = writeParquetVaults(nodes, seed)
+		if err != nil {
+			return err
+		}
+		stored := getAllStored(nodes)
+		deviation := stats.StandardDeviationFloat(stored)
+		largestShare := stats.Percentile(stored, 100) / stats.SumFloat(stored)
+		largestShares = append(largestShares, largestShare)
+		printRow(strconv.FormatInt(seed, 10), formatFloat(deviation), formatFloat(stats.Gini(stored)), formatFloat(stats.Percentile(stored, 100)/stats.AverageFloat(stored)), formatFloat(largestShare))
+		if len(deviations) == 0 || deviation > stats.Percentile(deviations, 100) {
+			worst = seed
+		}
+		if len(deviations) == 0 || deviation < stats.Percentile(deviations, 0) {
+			best = seed
+		}
+		deviations = append(deviations, deviation)
+	}
+	printMetric("Worst seed", worst)
+	printMetric("Best seed", best)
+	fmt.Println()
+	printHeader("percentile", "standard deviation of "+storageUnits+" stored")
+	for _, p := range []float64{0, 10, 50, 90, 100} {
+		printRow(strconv.FormatFloat(p, 'f', 0, 64), formatFloat(stats.Percentile(deviations, p)))
+	}
+	// the worst case for a single operator, one vault holding much of the
+	// network's data
+	fmt.Println()
+	printHeader("one vault storing more than percent of data", "probability")
+	for _, threshold := range thresholds {
+		printRow(strconv.FormatFloat(threshold, 'g', -1, 64), formatFloat(fractionAbove(largestShares, threshold/100)))
+	}
+	return nil
+}
+
+func fractionAbove(numbers []float64, threshold float64) float64 {
+	above := 0
+	for _, number := range numbers {
+		if number > threshold {
+			above += 1
+		}
+	}
+	return float64(above) / float64(len(numbers))
+}
+
+func reportMatrix(ctx context.Context, seed int64) error {
+	// spacing also decides where bestfit names vaults, so every pair is a
+	// separate run
+	if *matrixSeeds < 1 {
+		return ParameterError("matrix-seeds must be at least 1")
+	}
+	if *matrixSeeds > 1 {
+		return reportMatrixAcrossSeeds(ctx, seed, *matrixSeeds)
+	}
+	fmt.Println()
+	header := []string{"naming strategy", "spacing strategy", "standard deviation of spacings",
+		"standard deviation of " + storageUnits + " stored", "gini"}
+	if consensusModel != "none" {
+		header = append(header, "average messages per vault", "standard deviation of messages")
+	}
+	printHeader(header...)
+	for _, naming := range namingStrategies {
+		for _, spacing := range spacingStrategies {
+			namingStrategy = naming
+			spacingStrategy = spacing
+			startRun(seed)
+			nodes, _ := createNodes()
+			_, _, err := storeChunks(ctx, nodes, totalStored, false)
+			if err != nil {
+				return err
+			}
+			err = writeParquetVaults(nodes, seed)
+			if err != nil {
+				return err
+			}
+			sort.Sort(ByNodeName(nodes))
+			spacings := getAllSpacings(nodes)
+			stored := getAllStored(nodes)
+			row := []string{naming, spacing, strconv.FormatInt(stats.StandardDeviation(spacings), 10),
+				formatFloat(stats.StandardDeviationFloat(stored)), formatFloat(stats.Gini(stored))}
+			if consensusModel != "none" {
+				messages := []float64{}
+				for _, n := range nodes {
+					messages = append(messages, float64(n.Messages))
+				}
+				row = append(row, formatFloat(stats.AverageFloat(messages)), formatFloat(stats.StandardDeviationFloat(messages)))
+			}
+			printRow(row...)
+		}
+	}
+	return nil
+}
+
+func reportMatrixAcrossSeeds(ctx context.Context, first int64, seeds int) error {
+	// a strategy that is usually good but sometimes terrible has a low
+	// mean and a high spread, unlike one that is always mediocre
+	fmt.Println()
+	printHeader("naming strategy", "spacing strategy",
+		"mean standard deviation of "+storageUnits+" stored",
+		"standard deviation across seeds", "worst",
+		"mean gini", "standard deviation of gini across seeds", "worst gini")
+	for _, naming := range namingStrategies {
+		for _, spacing := range spacingStrategies {
+			namingStrategy = naming
+			spacingStrategy = spacing
+			deviations := []float64{}
+			ginis := []float64{}
+			for i := 0; i < seeds; i++ {
+				startRun(first + int64(i))
+				nodes, _ := createNodes()
+				_, _, err := storeChunks(ctx, nodes, totalStored, false)
+				if err != nil {
+					return err
+				}
+				err = writeParquetVaults(nodes, first+int64(i))
+				if err != nil {
+					return err
+				}
+				stored := getAllStored(nodes)
+				deviations = append(deviations, stats.StandardDeviationFloat(stored))
+				ginis = append(ginis, stats.Gini(stored))
+			}
+			printRow(naming, spacing,
+				formatFloat(stats.AverageFloat(deviations)), formatFloat(stats.StandardDeviationFloat(deviations)), formatFloat(stats.Percentile(deviations, 100)),
+				formatFloat(stats.AverageFloat(ginis)), formatFloat(stats.StandardDeviationFloat(ginis)), formatFloat(stats.Percentile(ginis, 100)))
+		}
+	}
+	return nil
+}
+
+func runFromStdin(ctx context.Context, seed int64) error {
+	defaults := defaultRunParams(seed)
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		params := defaults
+		err := json.Unmarshal([]byte(line), &params)
+		if err != nil {
+			encoder.Encode(RunResult{Error: "Invalid parameters: " + err.Error()})
+			continue
+		}
+		encoder.Encode(runWithParams(ctx, params))
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.New("Cannot read parameters: " + err.Error())
+	}
+	return nil
+}
+
+func defaultRunParams(seed int64) RunParams {
+	// parameters missing from a json object are taken from the flags
+	return RunParams{
+		Seed:            seed,
+		TotalNodes:      totalNodes,
+		TotalStored:     totalStored,
+		GroupSize:       groupSize,
+		NamingStrategy:  namingStrategy,
+		BestFitDivisor:  bestFitDivisor,
+		QuietestDepth:   quietestDepth,
+		SpacingStrategy: spacingStrategy,
+	}
+}
+
+func runWithParams(ctx context.Context, params RunParams) RunResult {
+	result, _ := simulateWithParams(ctx, params)
+	return result
+}
+
+func simulateWithParams(ctx context.Context, params RunParams) (RunResult, []Node) {
+	// also returns the vaults, which are nil if the run failed
+	result := RunResult{Params: &params}
+	if !isValidStrategy(params.NamingStrategy, namingStrategies) {
+		result.Error = "Invalid naming strategy"
+		return result, nil
+	}
+	if !isValidStrategy(params.SpacingStrategy, spacingStrategies) {
+		result.Error = "Invalid spacing strategy"
+		return result, nil
+	}
+	if params.TotalNodes < 1 || params.GroupSize < 1 || params.TotalStored < 1 || params.BestFitDivisor < 2 {
+		result.Error = "Invalid parameters"
+		return result, nil
+	}
+	if params.GroupSize > params.TotalNodes-elderCount<<sectionPrefixBits {
+		result.Error = "groupSize can't be more than the vaults that aren't elders"
+		return result, nil
+	}
+	if params.QuietestDepth < 1 || params.QuietestDepth > maxQuietestDepth {
+		result.Error = "quietestDepth must be from 1 to " + strconv.Itoa(int(maxQuietestDepth))
+		return result, nil
+	}
+	totalNodes = params.TotalNodes
+	totalStored = params.TotalStored
+	groupSize = params.GroupSize
+	namingStrategy = params.NamingStrategy
+	bestFitDivisor = params.BestFitDivisor
+	quietestDepth = params.QuietestDepth
+	spacingStrategy = params.SpacingStrategy
+	startRun(params.Seed)
+	nodes, _ := createNodes()
+	_, _, err := storeChunks(ctx, nodes, totalStored, false)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	stored := getAllStored(nodes)
+	avg := stats.AverageFloat(stored)
+	result.StandardDeviation = stats.StandardDeviationFloat(stored)
+	result.RelativeStandardDeviation = result.StandardDeviation / avg
+	result.Gini = stats.Gini(stored)
+	result.MaxOverAverage = stats.Percentile(stored, 100) / avg
+	result.MinOverAverage = stats.Percentile(stored, 0) / avg
+	return result, nodes
+}
+
+func isValidStrategy(strategy string, strategies []string) bool {
+	for _, s := range strategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+func reportStudy(ctx context.Context, name string, seed int64) error {
+	fmt.Println()
+	if name == "groupsize" {
+		printHeader(append([]string{"groupSize"}, balanceHeader()...)...)
+		initialGroupSize := groupSize
+		for _, size := range studyGroupSizes {
+			groupSize = size
+			startRun(seed)
+			nodes, _ := createNodes()
+			_, _, err := storeChunks(ctx, nodes, totalStored, false)
+			if err != nil {
+				return err
+			}
+			printRow(append([]string{strconv.Itoa(size)}, balanceMetrics(getAllStored(nodes))...)...)
+		}
+		groupSize = initialGroupSize
+	} else if name == "networksize" {
+		printHeader(append([]string{"namingStrategy", "totalNodes", "totalStored"}, balanceHeader()...)...)
+		initialNodes, initialStored, initialNaming := totalNodes, totalStored, namingStrategy
+		for _, naming := range namingStrategies {
+			namingStrategy = naming
+			for _, size := range studyNetworkSizes {
+				totalNodes = size
+				totalStored = initialStored * size / initialNodes
+				startRun(seed)
+				nodes, _ := createNodes()
+				_, _, err := storeChunks(ctx, nodes, totalStored, false)
+				if err != nil {
+					return err
+				}
+				printRow(append([]string{naming, strconv.Itoa(totalNodes), strconv.Itoa(totalStored)}, balanceMetrics(getAllStored(nodes))...)...)
+			}
+		}
+		totalNodes, totalStored, namingStrategy = initialNodes, initialStored, initialNaming
+	} else if name == "departure" {
+		return reportDepartureStudy(ctx, seed)
+	} else {
+		return ParameterError("Invalid study " + name)
+	}
+	return nil
+}
+
+func reportDepartureStudy(ctx context.Context, seed int64) error {
+	// Survivorship bias: when young vaults are more likely to leave, the
+	// vaults that remain have lasted through more relocations, which may
+	// favour some naming strategies over others. uniform naming never
+	// relocates so is the same under both, and is left out of the most
+	// balanced.
+	printHeader(append([]string{"departureModel", "namingStrategy", "average age of vaults"}, balanceHeader()...)...)
+	initialDeparture, initialNaming := departureModel, namingStrategy
+	best := map[string]string{}
+	for _, departure := range departureModels {
+		departureModel = departure
+		bestDeviation := math.Inf(1)
+		for _, naming := range namingStrategies {
+			namingStrategy = naming
+			startRun(seed)
+			nodes, _ := createNodes()
+			_, _, err := storeChunks(ctx, nodes, totalStored, false)
+			if err != nil {
+				return err
+			}
+			stored := getAllStored(nodes)
+			printRow(append([]string{departure, naming, formatFloat(averageAge(nodes))}, balanceMetrics(stored)...)...)
+			deviation := stats.StandardDeviationFloat(stored)
+			if naming != "uniform" && deviation < bestDeviation {
+				best[departure] = naming
+				bestDeviation = deviation
+			}
+		}
+	}
+	departureModel, namingStrategy = initialDeparture, initialNaming
+	fmt.Println()
+	printHeader("departureModel", "most balanced namingStrategy")
+	for _, departure := range departureModels {
+		printRow(departure, best[departure])
+	}
+	if best["uniform"] != best["young"] {
+		fmt.Println("\nDeparting young vaults changes which naming strategy is most balanced")
+	} else {
+		fmt.Println("\nDeparting young vaults doesn't change which naming strategy is most balanced")
+	}
+	return nil
+}
+
+func averageAge(nodes []Node) float64 {
+	total := 0.0
+	for _, age := range getAllAges(nodes) {
+		total += float64(age)
+	}
+	return total / float64(len(nodes))
+}
+
+func balanceHeader() []string {
+	return []string{"standard deviation of " + storageUnits + " stored",
+		"relative standard deviation", "gini", "max / average", "min / average"}
+}
+
+func balanceMetrics(stored []float64) []string {
+	avg := stats.AverageFloat(stored)
+	deviation := stats.StandardDeviationFloat(stored)
+	return []string{formatFloat(deviation), formatFloat(deviation / avg), formatFloat(stats.Gini(stored)),
+		formatFloat(stats.Percentile(stored, 100) / avg), formatFloat(stats.Percentile(stored, 0) / avg)}
+}
+
+func reportTuning(ctx context.Context) error {
+	fmt.Println()
+	if namingStrategy == "bestfit" {
+		printHeader("bestFitDivisor", "standard deviation of "+storageUnits+" stored")
+		best := bestFitDivisor
+		bestDeviation := math.Inf(1)
+		for _, divisor := range tuneBestFitDivisors {
+			bestFitDivisor = divisor
+			deviation, err := tuningDeviation(ctx)
+			if err != nil {
+				return err
+			}
+			printRow(strconv.FormatUint(divisor, 10), formatFloat(deviation))
+			if deviation < bestDeviation {
+				best = divisor
+				bestDeviation = deviation
+			}
+		}
+		printMetric("Best bestFitDivisor", best)
+	} else if namingStrategy == "quietesthalf" {
+		printHeader("quietestDepth", "standard deviation of "+storageUnits+" stored")
+		best := quietestDepth
+		bestDeviation := math.Inf(1)
+		for _, depth := range tuneQuietestDepths {
+			quietestDepth = depth
+			deviation, err := tuningDeviation(ctx)
+			if err != nil {
+				return err
+			}
+			printRow(strconv.FormatUint(uint64(depth), 10), formatFloat(deviation))
+			if deviation < bestDeviation {
+				best = depth
+				bestDeviation = deviation
+			}
+		}
+		printMetric("Best quietestDepth", best)
+	} else {
+		fmt.Println("No parameters to tune for " + namingStrategy + " naming")
+	}
+	return nil
+}
+
+func tuningDeviation(ctx context.Context) (float64, error) {
+	// average over several runs since a single run is noisy
+	total := 0.0
+	for i := 0; i < tuneTrials; i++ {
+		nodes, _ := createNodes()
+		_, _, err := storeChunks(ctx, nodes, tuneChunks, false)
+		if err != nil {
+			return 0, err
+		}
+		total += stats.StandardDeviationFloat(getAllStored(nodes))
+	}
+	return total / float64(tuneTrials), nil
+}
+
+func reportShrinking(ctx context.Context) error {
+	nodes, _ := createNodes()
+	chunks, holdings, err := storeChunks(ctx, nodes, shrinkChunks, true)
+	if err != nil {
+		return err
+	}
+	capacity := shrinkCapacity * stats.AverageFloat(getAllStored(nodes))
+	overCapacityAt := 0
+	lossAt := 0
+	fmt.Println()
+	printHeader("vaults", "average "+storageUnits+" stored", "max "+storageUnits+" stored", "chunks lost")
+	for len(nodes) > shrinkLeaves && len(nodes) > groupSize {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for i := 0; i < shrinkLeaves; i++ {
+			nodes = departWithChunks(nodes, departingNodeIndex(nodes), chunks, holdings)
+		}
+		for i := 0; i < shrinkJoins; i++ {
+			nodes = addNewNode(nodes)
+			logChurnEvent("join", nodes[len(nodes)-1].Name, "", len(nodes))
+		}
+		// the remaining vaults take over the chunks of those that left
+		refreshChunks(nodes, chunks, holdings)
+		stored := getAllStored(nodes)
+		most := stats.Percentile(stored, 100)
+		printRow(strconv.Itoa(len(nodes)), formatFloat(stats.AverageFloat(stored)), formatFloat(most), strconv.Itoa(chunksLost))
+		if overCapacityAt == 0 && most > capacity {
+			overCapacityAt = len(nodes)
+		}
+		if lossAt == 0 && chunksLost > 0 {
+			lossAt = len(nodes)
+		}
+	}
+	printMetric(fmt.Sprintf("Vaults left when a vault first exceeded %g times its starting average (0 if none did)", shrinkCapacity), overCapacityAt)
+	printMetric("Vaults left when data loss began (0 if no data was lost)", lossAt)
+	return nil
+}
+
+func reportSplits(ctx context.Context) error {
+	nodes, _ := createNodes()
+	sections := []Section{Section{Prefix: 0, Bits: 0}}
+	// the starting network splits before anything is stored, so costs nothing
+	for i := 0; i < len(sections); {
+		if split := splitSection(nodes, sections, i); split != nil {
+			sections = split
+		} else {
+			i += 1
+		}
+	}
+	chunks := []Chunk{}
+	for i := 0; i < sectionChunks; i++ {
+		if i%cancelCheckChunks == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		chunkName, _ := newChunkName()
+		chunks = append(chunks, Chunk{
+			Name:   chunkName,
+			Amount: getChunkAmount(),
+		})
+	}
+	fmt.Println()
+	printHeader("vaults", "section prefix", "vaults in section", storageUnits+" dropped", "chunks left with fewer copies")
+	splits := 0
+	totalDropped := 0.0
+	for i := 0; i < totalNodes; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		nodes = addNewNode(nodes)
+		logChurnEvent("join", nodes[len(nodes)-1].Name, "", len(nodes))
+		// only the section the vault joined can have become able to split
+		joined := sectionIndex(sections, nodes[len(nodes)-1].Name)
+		split := splitSection(nodes, sections, joined)
+		if split == nil {
+			continue
+		}
+		old := sections[joined]
+		dropped, short := droppedBySplit(nodes, chunks, old)
+		printRow(strconv.Itoa(len(nodes)), prefixStr(old.Prefix, old.Bits), strconv.Itoa(countInSection(nodes, old)), formatFloat(dropped), strconv.Itoa(short))
+		sections = split
+		splits += 1
+		totalDropped += dropped
+	}
+	printMetric("Sections at the end", len(sections))
+	printMetric("Splits while growing", splits)
+	averageDropped := 0.0
+	if splits > 0 {
+		averageDropped = totalDropped / float64(splits)
+	}
+	printMetric("Average "+storageUnits+" dropped per split", averageDropped)
+	if *mergeThreshold > 0 {
+		return reportMerges(ctx, nodes, sections, chunks)
+	}
+	return nil
+}
+
+func reportMerges(ctx context.Context, nodes []Node, sections []Section, chunks []Chunk) error {
+	fmt.Println()
+	printHeader("vaults", "merged prefix", "vaults in merged section", "chunks re-homed", storageUnits+" re-homed")
+	merges := 0
+	totalRehomed := 0.0
+	for len(sections) > 1 && len(nodes) > groupSize {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		index := departingNodeIndex(nodes)
+		name := nodes[index].Name
+		logChurnEvent("leave", name, "", len(nodes)-1)
+		nodes = removeNode(nodes, index)
+		// a merged section may itself be small enough to merge again
+		left := sectionIndex(sections, name)
+		for sections[left].Bits > 0 && countInSection(nodes, sections[left]) < *mergeThreshold {
+			merged := parentSection(sections[left])
+			rehomedChunks, rehomed := rehomedByMerge(nodes, chunks, sections, merged)
+			printRow(strconv.Itoa(len(nodes)), prefixStr(merged.Prefix, merged.Bits), strconv.Itoa(countInSection(nodes, merged)), strconv.Itoa(rehomedChunks), formatFloat(rehomed))
+			sections = mergeSection(sections, merged)
+			left = sectionIndex(sections, name)
+			merges += 1
+			totalRehomed += rehomed
+		}
+	}
+	printMetric("Sections at the end", len(sections))
+	printMetric("Merges while shrinking", merges)
+	averageRehomed := 0.0
+	if merges > 0 {
+		averageRehomed = totalRehomed / float64(merges)
+	}
+	printMetric("Average "+storageUnits+" re-homed per merge", averageRehomed)
+	return nil
+}
+
+func parentSection(s Section) Section {
+	return Section{
+		Prefix: s.Prefix &^ (uint64(1) << (64 - s.Bits)),
+		Bits:   s.Bits - 1,
+	}
+}
+
+func mergeSection(sections []Section, parent Section) []Section {
+	// Replaces every section within parent by parent, since the sibling of
+	// a section may have split further.
+	merged := []Section{}
+	added := false
+	for _, s := range sections {
+		if !samePrefix(s.Prefix, parent.Prefix, parent.Bits) {
+			merged = append(merged, s)
+		} else if !added {
+			merged = append(merged, parent)
+			added = true
+		}
+	}
+	return merged
+}
+
+func rehomedByMerge(nodes []Node, chunks []Chunk, sections []Section, parent Section) (int, float64) {
+	// Copies each chunk in the merged section needs that the closest group
+	// in its own section didn't hold. Nothing is dropped, since vaults in
+	// the chunk's own section are still the closest.
+	rehomedChunks := 0
+	rehomed := 0.0
+	for _, chunk := range chunks {
+		if !samePrefix(chunk.Name, parent.Prefix, parent.Bits) {
+			continue
+		}
+		own := sections[sectionIndex(sections, chunk.Name)]
+		before := map[uint64]bool{}
+		for _, n := range closestInSection(nodes, chunk.Name, own.Bits, groupSize) {
+			before[n.Name] = true
+		}
+		copies := 0
+		for _, n := range closestInSection(nodes, chunk.Name, parent.Bits, groupSize) {
+			if !before[n.Name] {
+				copies += 1
+			}
+		}
+		if copies > 0 {
+			rehomedChunks += 1
+			rehomed += float64(copies) * chunk.Amount
+		}
+	}
+	return rehomedChunks, rehomed
+}
+
+func splitSection(nodes []Node, sections []Section, index int) []Section {
+	// Returns the sections with the one at index split in two, or nil if
+	// either half would have fewer than split-threshold vaults.
+	s := sections[index]
+	if s.Bits == 64 {
+		return nil
+	}
+	lower := Section{Prefix: s.Prefix, Bits: s.Bits + 1}
+	upper := Section{Prefix: s.Prefix | uint64(1)<<(63-s.Bits), Bits: s.Bits + 1}
+	if countInSection(nodes, lower) < *splitThreshold || countInSection(nodes, upper) < *splitThreshold {
+		return nil
+	}
+	split := append([]Section{}, sections[0:index]...)
+	split = append(split, lower, upper)
+	return append(split, sections[index+1:]...)
+}
+
+func sectionIndex(sections []Section, name uint64) int {
+	// Sections are kept in prefix order and together cover every name, so
+	// the section of a name is the last one starting at or before it.
+	return sort.Search(len(sections), func(i int) bool {
+		return sections[i].Prefix > name
+	}) - 1
+}
+
+func countInSection(nodes []Node, s Section) int {
+	count := 0
+	for _, n := range nodes {
+		if samePrefix(n.Name, s.Prefix, s.Bits) {
+			count += 1
+		}
+	}
+	return count
+}
+
+func droppedBySplit(nodes []Node, chunks []Chunk, s Section) (float64, int) {
+	// Copies held in the other half of the section which are no longer
+	// needed once it splits. Xor distance already puts vaults in the same
+	// half closest, so the group after the split is always part of the
+	// group before and no copies move. A chunk only loses copies when its
+	// half has fewer than groupSize vaults, and every vault in that half
+	// already holds it, so there is nowhere to refill them.
+	dropped := 0.0
+	short := 0
+	for _, chunk := range chunks {
+		if !samePrefix(chunk.Name, s.Prefix, s.Bits) {
+			continue
+		}
+		before := len(closestInSection(nodes, chunk.Name, s.Bits, groupSize))
+		after := len(closestInSection(nodes, chunk.Name, s.Bits+1, groupSize))
+		dropped += float64(before-after) * chunk.Amount
+		if after < before {
+			short += 1
+		}
+	}
+	return dropped, short
+}
+
+func reportUploadChurn(ctx context.Context) error {
+	fmt.Println()
+	printHeader("departures per chunk stored", "fraction of chunks stored with fewer than groupSize copies")
+	for _, ratio := range uploadChurnRatios {
+		nodes, _ := createNodes()
+		short := 0
+		for i := 0; i < uploadChurnChunks; i++ {
+			if i%cancelCheckChunks == 0 && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			chunkName, _ := newChunkName()
+			group := []uint64{}
+			for _, n := range closestNodes(nodes, chunkName, groupSize) {
+				group = append(group, n.Name)
+			}
+			// a vault leaving before its copy is written leaves the chunk
+			// short, and is replaced so the network keeps its size
+			isShort := false
+			for d := poisson(ratio); d > 0; d-- {
+				index := departingNodeIndex(nodes)
+				for _, name := range group {
+					if nodes[index].Name == name {
+						isShort = true
+					}
+				}
+				nodes = removeNode(nodes, index)
+				nodes = addNewNode(nodes)
+			}
+			if isShort {
+				short += 1
+			}
+		}
+		printRow(fmt.Sprint(ratio), formatFloat(float64(short)/float64(uploadChurnChunks)))
+	}
+	return nil
+}
+
+func poisson(mean float64) int {
+	// number of events in an interval with this mean, by Knuth's method
+	limit := math.Exp(-mean)
+	k := 0
+	for p := rng.Float64(); p > limit; p *= rng.Float64() {
+		k += 1
+	}
+	return k
+}
+
+func reportDataLoss(trials int) {
+	fmt.Println()
+	fmt.Printf("Probability of any chunk losing all replicas (%d trials of %d chunks):\n", trials, lossTrialChunks)
+	header := []string{"churn rate"}
+	for _, replicas := range lossReplicaCounts {
+		header = append(header, fmt.Sprintf("replicas %d", replicas))
+	}
+	printHeader(header...)
+	for _, churnRate := range lossChurnRates {
+		row := []string{fmt.Sprint(churnRate)}
+		for _, replicas := range lossReplicaCounts {
+			row = append(row, formatFloat(dataLossProbability(churnRate, replicas, trials)))
+		}
+		printRow(row...)
+	}
+}
+
+func reportFailures(ctx context.Context, seed int64, trials int) error {
+	// Every stored chunk is lost if its whole close group fails, so only
+	// the distinct groups chunks were assigned to need checking.
+	losses := [][]float64{}
+	for _, naming := range namingStrategies {
+		namingStrategy = naming
+		startRun(seed)
+		nodes, _ := createNodes()
+		chunks, _, err := storeChunks(ctx, nodes, totalStored, true)
+		if err != nil {
+			return err
+		}
+		groups := distinctGroups(chunks)
+		names := []uint64{}
+		for _, n := range nodes {
+			names = append(names, n.Name)
+		}
+		probabilities := []float64{}
+		for k := 1; k <= 2*groupSize && k <= len(names); k++ {
+			lost := 0
+			for i := 0; i < trials; i++ {
+				failed := map[uint64]bool{}
+				for _, j := range rng.Perm(len(names))[0:k] {
+					failed[names[j]] = true
+				}
+				if anyGroupFailed(groups, failed) {
+					lost += 1
+				}
+			}
+			probabilities = append(probabilities, float64(lost)/float64(trials))
+		}
+		losses = append(losses, probabilities)
+	}
+	fmt.Println()
+	fmt.Printf("Probability of any chunk losing every copy when k vaults fail (%d trials):\n", trials)
+	printHeader(append([]string{"k"}, namingStrategies...)...)
+	for k := 1; k <= len(losses[0]); k++ {
+		row := []string{strconv.Itoa(k)}
+		for _, probabilities := range losses {
+			row = append(row, formatFloat(probabilities[k-1]))
+		}
+		printRow(row...)
+	}
+	return nil
+}
+
+func reportRouting(ctx context.Context, seed int64, trials int) error {
+	// A client sends a message through a random vault, and each vault
+	// forwards it to the vault it knows closest to the name until it
+	// reaches the close group. Sections are ignored, so this is routing
+	// over the names of the whole network as if it were a single section.
+	fmt.Println()
+	printHeader("naming strategy", "average hops", "50th percentile", "90th percentile", "most hops", "fraction of messages undelivered")
+	for _, naming := range namingStrategies {
+		namingStrategy = naming
+		startRun(seed)
+		nodes, _ := createNodes()
+		sort.Sort(ByNodeName(nodes))
+		names := []uint64{}
+		for _, n := range nodes {
+			names = append(names, n.Name)
+		}
+		tables := buildRoutingTables(names, groupSize)
+		hops := []float64{}
+		undelivered := 0
+		for i := 0; i < trials; i++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			target := rng.Uint64()
+			start := rng.Intn(len(names))
+			// closestNodes reorders the vaults it is given
+			group := map[uint64]bool{}
+			for _, n := range closestNodes(append([]Node{}, nodes...), target, groupSize) {
+				group[n.Name] = true
+			}
+			h, ok := routeHops(names, tables, start, target, group)
+			if !ok {
+				undelivered += 1
+				continue
+			}
+			// and one more from the client to the first vault
+			hops = append(hops, float64(h+1))
+		}
+		if len(hops) == 0 {
+			printRow(naming, "", "", "", "", formatFloat(1))
+			continue
+		}
+		printRow(naming, formatFloat(stats.AverageFloat(hops)),
+			formatFloat(stats.Percentile(hops, 50)), formatFloat(stats.Percentile(hops, 90)), formatFloat(stats.Percentile(hops, 100)),
+			formatFloat(float64(undelivered)/float64(trials)))
+	}
+	return nil
+}
+
+func buildRoutingTables(names []uint64, size int) [][]int {
+	// Each vault knows up to size of the closest vaults at each xor
+	// distance bucket, where the bucket is the highest bit of the distance,
+	// as in kademlia. names must be sorted, so each bucket is the range of
+	// names that share the bits above it and differ at it. Every vault is
+	// known to every other regardless of section.
+	tables := make([][]int, len(names))
+	for i, name := range names {
+		for bucket := 0; bucket < 64; bucket++ {
+			prefix := name>>bucket ^ 1
+			lo := sort.Search(len(names), func(j int) bool { return names[j]>>bucket >= prefix })
+			hi := sort.Search(len(names), func(j int) bool { return names[j]>>bucket > prefix })
+			tables[i] = append(tables[i], closestInRange(names, lo, hi, name, bucket-1, size, nil)...)
+		}
+	}
+	return tables
+}
+
+func closestInRange(names []uint64, lo int, hi int, name uint64, bit int, count int, closest []int) []int {
+	// Appends up to count of the sorted names from lo to hi, closest to name
+	// first. They share every bit above bit, so the closest are those
+	// matching name at bit, which come first or last in the range.
+	if lo == hi || len(closest) == count {
+		return closest
+	}
+	if hi-lo == 1 {
+		return append(closest, lo)
+	}
+	if bit < 0 {
+		// every bit matches, so the names are duplicates and equally close
+		for j := lo; j < hi && len(closest) < count; j++ {
+			closest = append(closest, j)
+		}
+		return closest
+	}
+	mid := lo + sort.Search(hi-lo, func(j int) bool { return names[lo+j]>>bit&1 == 1 })
+	if name>>bit&1 == 0 {
+		closest = closestInRange(names, lo, mid, name, bit-1, count, closest)
+		return closestInRange(names, mid, hi, name, bit-1, count, closest)
+	}
+	closest = closestInRange(names, mid, hi, name, bit-1, count, closest)
+	return closestInRange(names, lo, mid, name, bit-1, count, closest)
+}
+
+func routeHops(names []uint64, tables [][]int, start int, target uint64, group map[uint64]bool) (int, bool) {
+	// returns the hops between vaults from start to a member of group, or
+	// false if no vault known to the current one is closer to target
+	current := start
+	hops := 0
+	for !group[names[current]] {
+		next := current
+		for _, j := range tables[current] {
+			if group[names[j]] {
+				return hops + 1, true
+			}
+			if names[j]^target < names[next]^target {
+				next = j
+			}
+		}
+		if next == current {
+			return hops, false
+		}
+		current = next
+		hops += 1
+	}
+	return hops, true
+}
+
+func distinctGroups(chunks []Chunk) [][]uint64 {
+	// the holders of each chunk, counting each set of vaults once
+	groups := [][]uint64{}
+	seen := map[string]bool{}
+	for _, chunk := range chunks {
+		group := append([]uint64{}, chunk.Holders...)
+		sort.Sort(ByName(group))
+		key := fmt.Sprint(group)
+		if !seen[key] {
+			seen[key] = true
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func anyGroupFailed(groups [][]uint64, failed map[uint64]bool) bool {
+	for _, group := range groups {
+		all := true
+		for _, name := range group {
+			if !failed[name] {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+func dataLossProbability(churnRate float64, replicas int, trials int) float64 {
+	losses := 0
+	for i := 0; i < trials; i++ {
+		if trialLosesData(churnRate, replicas) {
+			losses += 1
+		}
+	}
+	return float64(losses) / float64(trials)
+}
+
+func trialLosesData(churnRate float64, replicas int) bool {
+	nodes := []Node{}
+	for i := 0; i < totalNodes; i++ {
+		nodes = addNewNode(nodes)
+	}
+	// choose which vaults leave
+	departed := map[uint64]bool{}
+	remaining := append([]Node{}, nodes...)
+	departures := int(churnRate * float64(totalNodes))
+	for i := 0; i < departures; i++ {
+		index := departingNodeIndex(remaining)
+		departed[remaining[index].Name] = true
+		remaining = append(remaining[0:index], remaining[index+1:]...)
+	}
+	// a chunk is lost if every vault holding it has left
+	for i := 0; i < lossTrialChunks; i++ {
+		chunkName, _ := newChunkName()
+		group := closestNodes(nodes, chunkName, replicas)
+		lost := true
+		for j, _ := range group {
+			if !departed[group[j].Name] {
+				lost = false
+				break
+			}
+		}
+		if lost {
+			return true
+		}
+	}
+	return false
+}
+
+func closestNodes(nodes []Node, chunkName uint64, count int) []Node {
+	return closestInSection(nodes, chunkName, sectionPrefixBits, count)
+}
+
+func closestInSection(nodes []Node, chunkName uint64, bits uint, count int) []Node {
+	// Returns up to count vaults sharing the leading bits of the chunk name,
+	// closest first, leaving out elders. The returned slice shares storage
+	// with nodes, which are reordered so vaults in the section come first.
+	section := nodes
+	if bits > 0 || elderCount > 0 {
+		inSection := 0
+		for j, _ := range nodes {
+			if samePrefix(nodes[j].Name, chunkName, bits) && !nodes[j].Elder {
+				nodes[inSection], nodes[j] = nodes[j], nodes[inSection]
+				inSection += 1
+			}
+		}
+		section = nodes[0:inSection]
+	}
+	return orderByXorDistance(section, chunkName, count)
+}
+
+func orderByXorDistance(section []Node, chunkName uint64, count int) []Node {
+	// returns the count vaults closest to the chunk name, closest first,
+	// using the placement backend
+	if placementBackend == "heap" {
+		// only the closest need sorting
+		if count < len(section) {
+			selectClosest(section, chunkName, count)
+			section = section[0:count]
+		}
+	}
+	xorSorter.Nodes = section
+	xorSorter.Target = chunkName
+	sort.Sort(xorSorter)
+	if len(section) > count {
+		section = section[0:count]
+	}
+	return section
+}
+
+func selectClosest(section []Node, chunkName uint64, count int) {
+	// Moves the count vaults closest to the chunk name to the front of
+	// section, in no particular order. The front is kept as a max heap by
+	// xor distance so the furthest of the closest so far is replaced.
+	if count <= 0 {
+		return
+	}
+	closest := section[0:count]
+	for i := count/2 - 1; i >= 0; i-- {
+		siftDownByXor(closest, i, chunkName)
+	}
+	for j := count; j < len(section); j++ {
+		if section[j].Name^chunkName < closest[0].Name^chunkName {
+			section[0], section[j] = section[j], section[0]
+			siftDownByXor(closest, 0, chunkName)
+		}
+	}
+}
+
+func siftDownByXor(heap []Node, i int, chunkName uint64) {
+	for {
+		furthest := i
+		left := 2*i + 1
+		right := left + 1
+		if left < len(heap) && heap[left].Name^chunkName > heap[furthest].Name^chunkName {
+			furthest = left
+		}
+		if right < len(heap) && heap[right].Name^chunkName > heap[furthest].Name^chunkName {
+			furthest = right
+		}
+		if furthest == i {
+			return
+		}
+		heap[i], heap[furthest] = heap[furthest], heap[i]
+		i = furthest
+	}
+}
+
+func sameSection(a, b uint64) bool {
+	return samePrefix(a, b, sectionPrefixBits)
+}
+
+func samePrefix(a, b uint64, bits uint) bool {
+	// names are in the same section if their prefixes match
+	return (a^b)>>(64-bits) == 0
+}
+
+func reportElders(nodes []Node) {
+	fmt.Println()
+	printHeader("role", "vaults", "average chunk "+storageUnits+" stored", "average metadata "+storageUnits+" stored")
+	for _, elder := range []bool{true, false} {
+		stored := []float64{}
+		metadata := []float64{}
+		for _, n := range nodes {
+			if n.Elder == elder {
+				stored = append(stored, n.Stored)
+				metadata = append(metadata, n.MetadataStored)
+			}
+		}
+		role := "adult"
+		if elder {
+			role = "elder"
+		}
+		if len(stored) > 0 {
+			printRow(role, strconv.Itoa(len(stored)), formatFloat(stats.AverageFloat(stored)), formatFloat(stats.AverageFloat(metadata)))
+		}
+	}
+}
+
+func reportSections(nodes []Node) {
+	fmt.Println()
+	printHeader("section prefix", "vaults", storageUnits+" stored", "standard deviation of "+storageUnits+" stored")
+	totalSections := uint64(1) << sectionPrefixBits
+	for i := uint64(0); i < totalSections; i++ {
+		prefix := i << (64 - sectionPrefixBits)
+		stored := []float64{}
+		total := 0.0
+		for _, n := range nodes {
+			if sameSection(n.Name, prefix) {
+				stored = append(stored, n.Stored)
+				total += n.Stored
+			}
+		}
+		deviation := 0.0
+		if len(stored) > 1 {
+			deviation = stats.StandardDeviationFloat(stored)
+		}
+		printRow(prefixStr(prefix, sectionPrefixBits), strconv.Itoa(len(stored)), formatFloat(total), formatFloat(deviation))
+	}
+}
+
+func prefixStr(prefix uint64, bits uint) string {
+	// binary, eg 01 for the second of four sections
+	s := ""
+	for i := uint(0); i < bits; i++ {
+		if prefix&(uint64(1)<<(63-i)) == 0 {
+			s += "0"
+		} else {
+			s += "1"
+		}
+	}
+	return s
+}
+
+func getChunkAmount() float64 {
+	// the amount each copy of a chunk adds to the storage of a vault
+	// storage units are checked before anything runs, so anything other
+	// than chunks is megabytes
+	if storageUnits == "chunks" {
+		return 1
+	}
+	return getRandomChunkSize()
+}
+
+func departWithChunks(nodes []Node, index int, chunks []Chunk, holdings map[uint64][]int) []Node {
+	// the departing vault takes its copies of chunks with it
+	dropHoldings(nodes[index].Name, chunks, holdings)
+	logChurnEvent("leave", nodes[index].Name, "", len(nodes)-1)
+	return removeNode(nodes, index)
+}
+
+func dropHoldings(name uint64, chunks []Chunk, holdings map[uint64][]int) {
+	// Removes the vault from the holders of every chunk it holds. holdings
+	// may list chunks the vault has since dropped, which are skipped.
+	for _, c := range holdings[name] {
+		holders := chunks[c].Holders
+		for h, holder := range holders {
+			if holder == name {
+				chunks[c].Holders = append(holders[0:h], holders[h+1:]...)
+				if len(chunks[c].Holders) == 0 {
+					chunksLost += 1
+				}
+				break
+			}
+		}
+	}
+	delete(holdings, name)
+}
+
+func relocateToOtherSection(nodes []Node, chunks []Chunk, holdings map[uint64][]int) {
+	// checked before anything runs, there is no other section to move to
+	if sectionPrefixBits == 0 {
+		return
+	}
+	index := rng.Intn(len(nodes))
+	// drop everything held in the old section
+	oldName := nodes[index].Name
+	previouslyMoved := relocationDropped + relocationFetched
+	relocationDropped += nodes[index].Stored
+	dropHoldings(nodes[index].Name, chunks, holdings)
+	// choose a different section
+	totalSections := uint64(1) << sectionPrefixBits
+	oldSection := nodes[index].Name >> (64 - sectionPrefixBits)
+	newSection := rng.Uint64() % (totalSections - 1)
+	if newSection >= oldSection {
+		newSection += 1
+	}
+	// take a random name in the new section
+	sortedNames(nodes)
+	minName := newSection << (64 - sectionPrefixBits)
+	maxName := minName | (math.MaxUint64 >> sectionPrefixBits)
+	newName := strategy.RandomNameBetween(rng, minName, maxName)
+	for nameIsIndexed(newName) {
+		vaultNameCollisions += 1
+		newName = strategy.RandomNameBetween(rng, minName, maxName)
+	}
+	traceNodeEvent("node_removed", oldName)
+	nodes[index].Name = newName
+	unindexName(oldName)
+	indexName(newName)
+	traceNodeEvent("node_added", newName)
+	logRenameEvent("relocation", oldName, newName, len(nodes))
+	nodes[index].Stored = 0
+	nodes[index].Chunks = 0
+	nodes[index].HotspotStored = 0
+	nodes[index].PrimaryStored = 0
+	// fetch chunks where the new name is closer than the furthest holder,
+	// which then drops the chunk
+	for c, _ := range chunks {
+		chunk := &chunks[c]
+		if len(chunk.Holders) == 0 || !sameSection(chunk.Name, newName) {
+			continue
+		}
+		furthest := 0
+		for h, holder := range chunk.Holders {
+			if holder^chunk.Name > chunk.Holders[furthest]^chunk.Name {
+				furthest = h
+			}
+		}
+		isShort := len(chunk.Holders) < groupSize
+		if !isShort && newName^chunk.Name > chunk.Holders[furthest]^chunk.Name {
+			continue
+		}
+		if !isShort {
+			dropped := chunk.Holders[furthest]
+			chunk.Holders = append(chunk.Holders[0:furthest], chunk.Holders[furthest+1:]...)
+			for j, _ := range nodes {
+				if nodes[j].Name == dropped {
+					nodes[j].Stored -= chunk.Amount
+					nodes[j].Chunks -= 1
+					break
+				}
+			}
+		}
+		chunk.Holders = append(chunk.Holders, newName)
+		holdings[newName] = append(holdings[newName], c)
+		nodes[index].Stored += chunk.Amount
+		nodes[index].Chunks += 1
+		relocationFetched += chunk.Amount
+	}
+	// both the dropped and fetched chunks are transferred
+	move := RelocationMove{
+		Name:  oldName,
+		Moved: relocationDropped + relocationFetched - previouslyMoved,
+	}
+	relocationMoves = append(relocationMoves, move)
+}
+
+func relocateWithChunks(nodes []Node, index int, chunks []Chunk, holdings map[uint64][]int) []Node {
+	// the chunks held by the relocating vault must be transferred to the
+	// vaults now responsible for them
+	age := nodes[index].Age
+	move := RelocationMove{
+		Name:  nodes[index].Name,
+		Moved: nodes[index].Stored,
+	}
+	// holdings may be stale, so only chunks still held can be kept
+	held := []int{}
+	for _, c := range holdings[move.Name] {
+		if nameIsTaken(move.Name, chunks[c].Holders) {
+			held = append(held, c)
+		}
+	}
+	dropHoldings(move.Name, chunks, holdings)
+	nodes = removeNode(nodes, index)
+	nodes = addNewNode(nodes)
+	nodes[len(nodes)-1].Age = age
+	newName := nodes[len(nodes)-1].Name
+	if *relocationRetention == "keep" {
+		for _, c := range held {
+			group := closestNodes(nodes, chunks[c].Name, groupSize)
+			for j, _ := range group {
+				if group[j].Name != newName {
+					continue
+				}
+				chunks[c].Holders = append(chunks[c].Holders, newName)
+				holdings[newName] = append(holdings[newName], c)
+				group[j].Stored += chunks[c].Amount
+				group[j].Chunks += 1
+				move.Kept += chunks[c].Amount
+				move.Moved -= chunks[c].Amount
+			}
+		}
+		// rounding can leave a tiny negative amount
+		move.Moved = math.Max(0, move.Moved)
+	}
+	relocationMoves = append(relocationMoves, move)
+	logRenameEvent("relocation", move.Name, newName, len(nodes))
+	return nodes
+}
+
+func reportAgeing() {
+	fmt.Println()
+	printHeader("age at relocation", "relocations")
+	ages := []int{}
+	total := 0
+	for age, count := range relocationsByAge {
+		ages = append(ages, age)
+		total += count
+	}
+	sort.Ints(ages)
+	for _, age := range ages {
+		printRow(strconv.Itoa(age), strconv.Itoa(relocationsByAge[age]))
+	}
+	perEvent := 0.0
+	if ageingEvents > 0 {
+		perEvent = float64(total) / float64(ageingEvents)
+	}
+	printMetric("Relocations per join or leave", perEvent)
+}
+
+func reportRelocationMoves() {
+	fmt.Println()
+	printHeader("relocation", "vault name", storageUnits+" moved", storageUnits+" kept")
+	total := 0.0
+	kept := 0.0
+	for i, move := range relocationMoves {
+		printRow(strconv.Itoa(i+1), nameStr(move.Name), formatFloat(move.Moved), formatFloat(move.Kept))
+		total += move.Moved
+		kept += move.Kept
+	}
+	printMetric("Total moved by relocations with "+namingStrategy+" naming and "+*relocationRetention+" retention ("+storageUnits+")", total)
+	printMetric("Total kept by relocated vaults ("+storageUnits+")", kept)
+}
+
+func reportCrossSectionRelocations() {
+	printMetric("Dropped by relocated vaults ("+storageUnits+")", relocationDropped)
+	printMetric("Fetched by relocated vaults ("+storageUnits+")", relocationFetched)
+	moved := relocationDropped + relocationFetched
+	printMetric("Average moved per relocation ("+storageUnits+")", moved/float64(crossSectionRelocations))
+}
+
+func getWithReadRepair(nodes []Node, chunks []Chunk, holdings map[uint64][]int) []Node {
+	c := rng.Intn(len(chunks))
+	chunk := &chunks[c]
+	// a chunk with no copies left cannot be fetched or repaired, and a chunk
+	// with all copies needs no repair
+	if len(chunk.Holders) == 0 || len(chunk.Holders) >= groupSize {
+		return nodes
+	}
+	// copy the chunk to the closest vaults that do not already hold it
+	candidates := closestNodes(nodes, chunk.Name, len(nodes))
+	for j := 0; j < len(candidates) && len(chunk.Holders) < groupSize; j++ {
+		if nameIsTaken(candidates[j].Name, chunk.Holders) {
+			continue
+		}
+		chunk.Holders = append(chunk.Holders, candidates[j].Name)
+		holdings[candidates[j].Name] = append(holdings[candidates[j].Name], c)
+		candidates[j].Stored += chunk.Amount
+		candidates[j].Chunks += 1
+		repairTraffic += chunk.Amount
+	}
+	chunksRepaired += 1
+	return nodes
+}
+
+func measureTemperature(nodes []Node, chunks []Chunk) {
+	// popularity ranks are given to chunks in a random order, so popular
+	// chunks are anywhere in the keyspace
+	if len(chunks) == 0 {
+		return
+	}
+	ranks := rng.Perm(len(chunks))
+	zipf := rand.NewZipf(rng, getZipfExponent, 1, uint64(len(chunks)-1))
+	for i := 0; i < getPhaseRequests; i++ {
+		chunks[ranks[zipf.Uint64()]].Gets += 1
+	}
+	indexes := map[uint64]int{}
+	for i, n := range nodes {
+		indexes[n.Name] = i
+	}
+	for _, chunk := range chunks {
+		if chunk.Gets > 0 {
+			continue
+		}
+		for _, holder := range chunk.Holders {
+			// archive vaults aren't in nodes
+			if i, ok := indexes[holder]; ok {
+				nodes[i].ColdStored += chunk.Amount
+			}
+		}
+	}
+}
+
+func chunkTemperature(chunk Chunk) string {
+	if chunk.Gets >= hotGets {
+		return "hot"
+	} else if chunk.Gets > 0 {
+		return "warm"
+	}
+	return "cold"
+}
+
+func reportTemperature(nodes []Node) {
+	fmt.Println()
+	printHeader("temperature", "chunks", storageUnits+" stored including copies")
+	counts := map[string]int{}
+	stored := map[string]float64{}
+	for _, chunk := range assignedChunks {
+		t := chunkTemperature(chunk)
+		counts[t] += 1
+		stored[t] += chunk.Amount * float64(len(chunk.Holders))
+	}
+	for _, t := range []string{"hot", "warm", "cold"} {
+		printRow(t, strconv.Itoa(counts[t]), formatFloat(stored[t]*math.Max(1, sampleScale)))
+	}
+	cold := []float64{}
+	for _, n := range nodes {
+		if n.Stored > 0 {
+			cold = append(cold, n.ColdStored/n.Stored)
+		}
+	}
+	printMetric("Average fraction of vault storage that is cold", stats.AverageFloat(cold))
+	printMetric("Most of a vault's storage that is cold (fraction)", stats.Percentile(cold, 100))
+	printMetric("Least of a vault's storage that is cold (fraction)", stats.Percentile(cold, 0))
+}
+
+func countUnderReplicated(chunks []Chunk) int {
+	underReplicated := 0
+	for _, chunk := range chunks {
+		if len(chunk.Holders) > 0 && len(chunk.Holders) < groupSize {
+			underReplicated += 1
+		}
+	}
+	return underReplicated
+}
+
+func restartNode(nodes []Node, chunks []Chunk, holdings map[uint64][]int) []Node {
+	// A vault returning with the same name keeps its data so costs nothing.
+	// With a new name its data is dropped and must be restored by
+	// read-repair or refresh.
+	index := departingNodeIndex(nodes)
+	oldName := nodes[index].Name
+	restarts += 1
+	if *restartPolicy == "same" {
+		logRenameEvent("restart", oldName, oldName, len(nodes))
+		return nodes
+	}
+	restartDropped += nodes[index].Stored
+	dropHoldings(oldName, chunks, holdings)
+	nodes = removeNode(nodes, index)
+	nodes = addNewNode(nodes)
+	newName := nodes[len(nodes)-1].Name
+	logRenameEvent("restart", oldName, newName, len(nodes))
+	return nodes
+}
+
+func refreshChunks(nodes []Node, chunks []Chunk, holdings map[uint64][]int) {
+	// The closest group of every chunk confirms it holds the chunk. Members
+	// missing it get a copy from the closest holder, and holders outside
+	// the group drop it.
+	for c, _ := range chunks {
+		chunk := &chunks[c]
+		if len(chunk.Holders) == 0 {
+			continue
+		}
+		all := closestNodes(nodes, chunk.Name, len(nodes))
+		var sender *Node
+		for j, _ := range all {
+			if nameIsTaken(all[j].Name, chunk.Holders) {
+				sender = &all[j]
+				break
+			}
+		}
+		rehomed := false
+		for j, _ := range all {
+			holds := nameIsTaken(all[j].Name, chunk.Holders)
+			if j < groupSize && holds {
+				refreshConfirmations += 1
+			} else if j < groupSize {
+				chunk.Holders = append(chunk.Holders, all[j].Name)
+				holdings[all[j].Name] = append(holdings[all[j].Name], c)
+				all[j].Stored += chunk.Amount
+				all[j].Chunks += 1
+				all[j].RefreshTraffic += chunk.Amount
+				sender.RefreshTraffic += chunk.Amount
+				rehomed = true
+			} else if holds {
+				for h, holder := range chunk.Holders {
+					if holder == all[j].Name {
+						chunk.Holders = append(chunk.Holders[0:h], chunk.Holders[h+1:]...)
+						break
+					}
+				}
+				all[j].Stored -= chunk.Amount
+				all[j].Chunks -= 1
+			}
+		}
+		if rehomed {
+			chunksRehomed += 1
+		}
+	}
+	refreshes += 1
+}
+
+func reportRefresh(nodes []Node) {
+	traffic := []float64{}
+	for _, n := range nodes {
+		traffic = append(traffic, n.RefreshTraffic)
+	}
+	printMetric("Refreshes", refreshes)
+	printMetric("Refresh confirmations", refreshConfirmations)
+	printMetric("Chunks re-homed by refresh", chunksRehomed)
+	printMetric("Average refresh traffic per vault ("+storageUnits+")", stats.AverageFloat(traffic))
+	printMetric("Standard deviation of refresh traffic per vault ("+storageUnits+")", stats.StandardDeviationFloat(traffic))
+	printMetric("Most refresh traffic for a vault ("+storageUnits+")", stats.Percentile(traffic, 100))
+}
+
+func reportReadRepair() {
+	printMetric("Read-repair traffic ("+storageUnits+")", repairTraffic)
+	printMetric("Chunks repaired", chunksRepaired)
+	printMetric("Chunks lost", chunksLost)
+	fmt.Println()
+	printHeader("churn event", "under-replicated chunks")
+	for i, underReplicated := range underReplicatedAfterEvent {
+		printRow(strconv.Itoa(i+1), strconv.Itoa(underReplicated))
+	}
+}
+
+func reportRepairSweep(ctx context.Context, seed int64, events int) error {
+	// Every GET rate churns from the same seed. Recovery keeps making GETs
+	// at the same rate once churn stops, counted in churn events' worth of
+	// GETs, until no chunk with a copy left is short of copies.
+	if namingStrategy == "uniform" {
+		return ParameterError("repair-sweep needs vaults to rejoin, which uniform naming can't do")
+	}
+	fmt.Println()
+	printHeader("GETs per churn event", "read-repair traffic ("+storageUnits+")",
+		"chunks repaired", "chunks lost", "average under-replicated chunks after each event",
+		"under-replicated chunks when churn stops", "churn events of GETs until fully replicated")
+	for _, rate := range repairGetRates {
+		startRun(seed)
+		nodes, _ := createNodes()
+		chunks, holdings, err := storeChunks(ctx, nodes, totalStored, true)
+		if err != nil {
+			return err
+		}
+		underReplicated := []float64{}
+		for i := 0; i < events; i++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			index := departingNodeIndex(nodes)
+			left := nodes[index].Name
+			nodes = departWithChunks(nodes, index, chunks, holdings)
+			nodes = addNewNode(nodes)
+			joined := nodes[len(nodes)-1].Name
+			relocate := func(nodes []Node, index int) []Node {
+				return relocateWithChunks(nodes, index, chunks, holdings)
+			}
+			nodes, _ = relocateForEvent(nodes, left, relocate)
+			nodes, _ = relocateForEvent(nodes, joined, relocate)
+			for j := 0; j < rate; j++ {
+				nodes = getWithReadRepair(nodes, chunks, holdings)
+			}
+			underReplicated = append(underReplicated, float64(countUnderReplicated(chunks)))
+		}
+		traffic, repaired, lost := repairTraffic, chunksRepaired, chunksLost
+		// each repair copies the chunk to every missing member of its group
+		remaining := countUnderReplicated(chunks)
+		recovery := "never"
+		if remaining == 0 {
+			recovery = "0"
+		}
+		for e := 1; e <= repairRecoveryEvents && remaining > 0 && rate > 0; e++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			for j := 0; j < rate; j++ {
+				before := chunksRepaired
+				nodes = getWithReadRepair(nodes, chunks, holdings)
+				remaining -= chunksRepaired - before
+			}
+			if remaining <= 0 {
+				recovery = strconv.Itoa(e)
+			} else if e == repairRecoveryEvents {
+				recovery = "over " + strconv.Itoa(repairRecoveryEvents)
+			}
+		}
+		printRow(strconv.Itoa(rate), formatFloat(traffic), strconv.Itoa(repaired), strconv.Itoa(lost),
+			formatFloat(stats.AverageFloat(underReplicated)), strconv.Itoa(int(underReplicated[len(underReplicated)-1])), recovery)
+	}
+	return nil
+}
+
+func closeGroupChunks(nodes []Node, chunks []Chunk, vault Node) []int {
+	// Returns the chunks for which the vault is one of the groupSize
+	// closest in its section. These are the chunks whose close group
+	// changes when the vault joins or leaves, whether or not any copy has
+	// moved yet. nodes includes the vault.
+	inGroup := []int{}
+	if vault.Elder {
+		return inGroup
+	}
+	for c, chunk := range chunks {
+		if !samePrefix(vault.Name, chunk.Name, sectionPrefixBits) {
+			continue
+		}
+		distance := vault.Name ^ chunk.Name
+		closer := 0
+		for _, n := range nodes {
+			if n.Name^chunk.Name < distance && !n.Elder && samePrefix(n.Name, chunk.Name, sectionPrefixBits) {
+				closer += 1
+				if closer == groupSize {
+					break
+				}
+			}
+		}
+		if closer < groupSize {
+			inGroup = append(inGroup, c)
+		}
+	}
+	return inGroup
+}
+
+func markGroupChanges(nodes []Node, chunks []Chunk, vault Node, event int) int {
+	// records event as the first change of close group for the chunks of
+	// the joining or leaving vault, returning how many chunks it changes
+	changed := closeGroupChunks(nodes, chunks, vault)
+	for _, c := range changed {
+		if groupFirstChanged[c] == 0 {
+			groupFirstChanged[c] = event
+		}
+	}
+	return len(changed)
+}
+
+func relocateMarkingGroupChanges(chunks []Chunk, event int, relocate func([]Node, int) []Node) func([]Node, int) []Node {
+	// a relocated vault leaves one close group and joins another
+	return func(nodes []Node, index int) []Node {
+		markGroupChanges(nodes, chunks, nodes[index], event)
+		nodes = relocate(nodes, index)
+		markGroupChanges(nodes, chunks, nodes[len(nodes)-1], event)
+		return nodes
+	}
+}
+
+func eventsUntilGroupsChange(fraction float64) int {
+	// the churn event by which fraction of the stored chunks had changed
+	// close group, or 0 if too few have changed yet
+	changed := []int{}
+	for _, event := range groupFirstChanged {
+		if event > 0 {
+			changed = append(changed, event)
+		}
+	}
+	needed := int(math.Ceil(fraction * float64(len(groupFirstChanged))))
+	if needed == 0 || len(changed) < needed {
+		return 0
+	}
+	sort.Ints(changed)
+	return changed[needed-1]
+}
+
+func formatEventsUntil(events int, churned int) string {
+	if events == 0 {
+		return fmt.Sprintf("more than %d", churned)
+	}
+	return fmt.Sprintf("%d", events)
+}
+
+func reportGroupChanges() {
+	// the cost of each membership change, which depends on how evenly
+	// vault names are spread
+	printMetric("Average chunks changing close group per join or leave", stats.AverageFloat(groupChangesPerEvent))
+	for _, p := range []float64{10, 50, 90} {
+		printMetric(fmt.Sprintf("%gth percentile of chunks changing close group per join or leave", p), stats.Percentile(groupChangesPerEvent, p))
+	}
+	printMetric("Most chunks changing close group for a join or leave", stats.Percentile(groupChangesPerEvent, 100))
+	// how long chunks stay with the group they were stored by
+	churned := len(groupChangesPerEvent) / 2
+	for _, fraction := range []float64{0.1, 0.5, 0.9} {
+		printMetric(fmt.Sprintf("Churn events until %g%% of chunks changed close group", fraction*100), formatEventsUntil(eventsUntilGroupsChange(fraction), churned))
+	}
+}
+
+func reportHandoff(ctx context.Context, seed int64, events int) error {
+	// Each chunk stays with the vaults that stored it and only moves when a
+	// holder leaves or a closer vault joins. When every handoff happens the
+	// holders are exactly the recomputed close group, so any difference in
+	// the table is a copy that was missed.
+	fmt.Println()
+	printHeader("naming strategy",
+		"standard deviation of "+storageUnits+" stored with handoff",
+		"standard deviation recomputed", "gini with handoff", "gini recomputed",
+		"fraction of copies outside the close group")
+	for _, naming := range namingStrategies {
+		// as for half-life, uniform names cannot rejoin
+		if naming == "uniform" {
+			continue
+		}
+		namingStrategy = naming
+		startRun(seed)
+		nodes, _ := createNodes()
+		chunks, holdings, err := storeChunks(ctx, nodes, totalStored, true)
+		if err != nil {
+			return err
+		}
+		for event := 1; event <= events; event++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			index := departingNodeIndex(nodes)
+			left := nodes[index].Name
+			nodes = handOffChunks(nodes, index, chunks, holdings)
+			nodes = addNewNode(nodes)
+			handOnChunks(nodes, len(nodes)-1, chunks, holdings)
+			joined := nodes[len(nodes)-1].Name
+			relocate := func(nodes []Node, index int) []Node {
+				age := nodes[index].Age
+				nodes = handOffChunks(nodes, index, chunks, holdings)
+				nodes = addNewNode(nodes)
+				nodes[len(nodes)-1].Age = age
+				handOnChunks(nodes, len(nodes)-1, chunks, holdings)
+				return nodes
+			}
+			nodes, _ = relocateForEvent(nodes, left, relocate)
+			nodes, _ = relocateForEvent(nodes, joined, relocate)
+		}
+		// the same chunks stored by the close groups of the final network
+		recomputed := map[uint64]float64{}
+		outside := 0
+		copies := 0
+		for _, chunk := range chunks {
+			group := closestNodes(nodes, chunk.Name, groupSize)
+			for j, _ := range group {
+				recomputed[group[j].Name] += chunk.Amount
+			}
+			for _, holder := range chunk.Holders {
+				if !nodeIsInGroup(holder, group) {
+					outside += 1
+				}
+			}
+			copies += len(chunk.Holders)
+		}
+		cached := getAllStored(nodes)
+		ideal := []float64{}
+		for _, n := range nodes {
+			ideal = append(ideal, recomputed[n.Name])
+		}
+		printRow(naming,
+			formatFloat(stats.StandardDeviationFloat(cached)), formatFloat(stats.StandardDeviationFloat(ideal)),
+			formatFloat(stats.Gini(cached)), formatFloat(stats.Gini(ideal)), formatFloat(float64(outside)/float64(copies)))
+	}
+	return nil
+}
+
+func handOffChunks(nodes []Node, index int, chunks []Chunk, holdings map[uint64][]int) []Node {
+	// The departing vault hands each chunk it holds to the closest vault
+	// not already holding it, and the other holders keep their copies.
+	// A chunk dropped and taken again is listed twice in holdings.
+	name := nodes[index].Name
+	held := []int{}
+	isHeld := map[int]bool{}
+	for _, c := range holdings[name] {
+		if !isHeld[c] && nameIsTaken(name, chunks[c].Holders) {
+			held = append(held, c)
+			isHeld[c] = true
+		}
+	}
+	dropHoldings(name, chunks, holdings)
+	nodes = removeNode(nodes, index)
+	for _, c := range held {
+		chunk := &chunks[c]
+		candidates := closestNodes(nodes, chunk.Name, len(chunk.Holders)+1)
+		for j, _ := range candidates {
+			if nameIsTaken(candidates[j].Name, chunk.Holders) {
+				continue
+			}
+			chunk.Holders = append(chunk.Holders, candidates[j].Name)
+			holdings[candidates[j].Name] = append(holdings[candidates[j].Name], c)
+			candidates[j].Stored += chunk.Amount
+			candidates[j].Chunks += 1
+			break
+		}
+	}
+	return nodes
+}
+
+func handOnChunks(nodes []Node, index int, chunks []Chunk, holdings map[uint64][]int) {
+	// The joining vault takes each chunk it is closer to than the furthest
+	// holder, which then drops its copy, or any chunk with too few holders.
+	name := nodes[index].Name
+	for c, _ := range chunks {
+		chunk := &chunks[c]
+		if len(chunk.Holders) == 0 {
+			continue
+		}
+		furthest := 0
+		for h, holder := range chunk.Holders {
+			if holder^chunk.Name > chunk.Holders[furthest]^chunk.Name {
+				furthest = h
+			}
+		}
+		isShort := len(chunk.Holders) < groupSize
+		if !isShort && name^chunk.Name > chunk.Holders[furthest]^chunk.Name {
+			continue
+		}
+		if !isShort {
+			dropped := chunk.Holders[furthest]
+			chunk.Holders = append(chunk.Holders[0:furthest], chunk.Holders[furthest+1:]...)
+			for j, _ := range nodes {
+				if nodes[j].Name == dropped {
+					nodes[j].Stored -= chunk.Amount
+					nodes[j].Chunks -= 1
+					break
+				}
+			}
+		}
+		chunk.Holders = append(chunk.Holders, name)
+		holdings[name] = append(holdings[name], c)
+		nodes[index].Stored += chunk.Amount
+		nodes[index].Chunks += 1
+	}
+}
+
+func nodeIsInGroup(name uint64, group []Node) bool {
+	for _, n := range group {
+		if n.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func reportHalfLife(ctx context.Context, seed int64, events int) error {
+	// every naming strategy churns from the same seed, and only the names
+	// matter so no chunks are moved
+	fmt.Println()
+	printHeader("naming strategy", "churn events until 10% of chunks changed close group",
+		"churn events until half changed", "churn events until 90% changed",
+		"fraction of chunks never changed")
+	for _, naming := range namingStrategies {
+		// uniform names are spaced for a network formed once, so a vault
+		// joining after a leave takes a name that is already in use
+		if naming == "uniform" {
+			continue
+		}
+		namingStrategy = naming
+		startRun(seed)
+		nodes, _ := createNodes()
+		chunks, _, err := storeChunks(ctx, nodes, totalStored, true)
+		if err != nil {
+			return err
+		}
+		groupFirstChanged = make([]int, len(chunks))
+		for event := 1; event <= events; event++ {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			index := departingNodeIndex(nodes)
+			left := nodes[index].Name
+			markGroupChanges(nodes, chunks, nodes[index], event)
+			nodes = removeNode(nodes, index)
+			nodes = addNewNode(nodes)
+			joined := nodes[len(nodes)-1].Name
+			markGroupChanges(nodes, chunks, nodes[len(nodes)-1], event)
+			relocate := relocateMarkingGroupChanges(chunks, event, relocateNode)
+			nodes, _ = relocateForEvent(nodes, left, relocate)
+			nodes, _ = relocateForEvent(nodes, joined, relocate)
+		}
+		unchanged := 0
+		for _, event := range groupFirstChanged {
+			if event == 0 {
+				unchanged += 1
+			}
+		}
+		printRow(naming,
+			formatEventsUntil(eventsUntilGroupsChange(0.1), events),
+			formatEventsUntil(eventsUntilGroupsChange(0.5), events),
+			formatEventsUntil(eventsUntilGroupsChange(0.9), events),
+			formatFloat(float64(unchanged)/float64(len(chunks))))
+	}
+	return nil
+}
+
+func relocationsForEvent() int {
+	return countForRate(relocationRate)
+}
+
+func countForRate(rate float64) int {
+	// the whole part of the rate always happens
+	count := int(rate)
+	// the fractional part is the chance of one more
+	if rng.Float64() < rate-float64(count) {
+		count += 1
+	}
+	return count
+}
+
+func newChunkName() (uint64, bool) {
+	name := rng.Uint64()
+	// force some chunks into the hotspot
+	if hotspotBits > 0 && rng.Float64() < hotspotFraction {
+		name = hotspotPrefix | (name >> hotspotBits)
+		return name, true
+	}
+	return name, false
+}
+
+func parseHotspot(s string) (uint64, uint, float64, error) {
+	// eg a3,0.2
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, 0, ParameterError("Invalid hotspot, must be prefix,fraction")
+	}
+	prefixHex := parts[0]
+	if len(prefixHex) == 0 || len(prefixHex) > 16 {
+		return 0, 0, 0, ParameterError("Invalid hotspot prefix length")
+	}
+	prefix, err := strconv.ParseUint(prefixHex, 16, 64)
+	if err != nil {
+		return 0, 0, 0, ParameterError("Invalid hotspot prefix")
+	}
+	fraction, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || fraction < 0 || fraction > 1 {
+		return 0, 0, 0, ParameterError("Invalid hotspot fraction")
+	}
+	// each hex character of the prefix fixes 4 bits of the name
+	bits := uint(len(prefixHex) * 4)
+	return prefix << (64 - bits), bits, fraction, nil
+}
+
+func reportHotspot(nodes []Node) {
+	// compare the vaults storing hotspot chunks with the rest
+	hotspotVaults := 0
+	hotspotTotal := 0.0
+	otherTotal := 0.0
+	for _, n := range nodes {
+		if n.HotspotStored > 0 {
+			hotspotVaults += 1
+			hotspotTotal += n.Stored
+		} else {
+			otherTotal += n.Stored
+		}
+	}
+	otherVaults := len(nodes) - hotspotVaults
+	printMetric("Vaults storing hotspot chunks", hotspotVaults)
+	printMetric("Average "+storageUnits+" stored by hotspot vaults", hotspotTotal/float64(hotspotVaults))
+	otherAverage := 0.0
+	if otherVaults > 0 {
+		otherAverage = otherTotal / float64(otherVaults)
+	}
+	printMetric("Average "+storageUnits+" stored by other vaults", otherAverage)
+}
+
+func reportSubsections(nodes []Node) {
+	vaults, totals := getSubsectionTotals(nodes, subsectionDepth)
+	fmt.Println()
+	printHeader("subsection start", "vaults", storageUnits+" stored")
+	for i := range totals {
+		start := uint64(i) << (64 - subsectionDepth)
+		printRow(nameStr(start), strconv.Itoa(vaults[i]), formatFloat(totals[i]))
+	}
+}
+
+func getSubsectionTotals(nodes []Node, depth uint) ([]int, []float64) {
+	totalSubsections := uint64(1) << depth
+	vaults := make([]int, totalSubsections)
+	totals := make([]float64, totalSubsections)
+	for _, n := range nodes {
+		// the leading bits of the name are the subsection index
+		i := n.Name >> (64 - depth)
+		vaults[i] += 1
+		totals[i] += n.Stored
+	}
+	return vaults, totals
+}
+
+func nameStr(i uint64) string {
+	if *nameFormat == "base32" {
+		// 13 characters, the last holding the remaining 4 bits and a zero
+		// of padding
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, i)
+		return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	} else if *nameFormat == "binary" {
+		s := strconv.FormatUint(i, 2)
+		return strings.Repeat("0", 64-len(s)) + s
+	} else if *nameFormat == "xorname" {
+		// names are read back from the leading 64 bits by parseName
+		return hexName(i) + strings.Repeat("0", 48)
+	}
+	return hexName(i)
+}
+
+func hexName(i uint64) string {
+	s := strconv.FormatUint(i, 16)
+	for len(s) < 16 {
+		s = "0" + s
+	}
+	return s
+}
+
+func getAllStored(nodes []Node) []float64 {
+	stored := []float64{}
+	for _, node := range nodes {
+		stored = append(stored, node.Stored)
+	}
+	return stored
+}
+
+func reportExpectations(n int) {
+	// linear spacings, as measured by spacingsBy
+	maxName := float64(math.MaxUint64)
+	var spacingDeviation float64
+	if namingStrategy == "uniform" {
+		// the first spacing is 0 and the remaining n spacings are all
+		// maxName / n
+		spacingDeviation = maxName / float64(n) / math.Sqrt(float64(n+1))
+	} else {
+		// n+1 gaps between n uniformly random names, each distributed as
+		// maxName * Beta(1, n)
+		nf := float64(n)
+		spacingDeviation = maxName * math.Sqrt(nf/((nf+1)*(nf+1)*(nf+2)))
+	}
+	printMetric("Expected standard deviation of linear spacings", int64(spacingDeviation))
+	// load, where each chunk lands on each vault with probability p
+	meanSize := 1.0
+	meanSquareSize := 1.0
+	if storageUnits == "megabytes" {
+		meanSize, meanSquareSize = chunkSizeMoments()
+	}
+	p := float64(groupSize) / float64(n)
+	chunks := float64(totalStored)
+	printMetric("Expected average "+storageUnits+" stored per vault", chunks*p*meanSize)
+	// with uniform names every vault has an equal share so the only
+	// variation is from the random chunk names and sizes
+	if namingStrategy == "uniform" {
+		variance := chunks * (p*meanSquareSize - p*p*meanSize*meanSize)
+		printMetric("Expected standard deviation of "+storageUnits+" stored per vault", math.Sqrt(variance))
+	}
+}
+
+func reportExtremeSpacings(nodes []Node) {
+	// the first spacing starts at 0 and the last ends at the top of the
+	// namespace, as in spacings.csv
+	fmt.Println()
+	printHeader("spacing", "extreme", "from", "to", "size")
+	for _, spacingBy := range spacingStrategies {
+		spacings := spacingsBy(nodes, spacingBy)
+		largest := 0
+		smallest := 0
+		for i, spacing := range spacings {
+			if spacing > spacings[largest] {
+				largest = i
+			}
+			if spacing < spacings[smallest] {
+				smallest = i
+			}
+		}
+		for _, extreme := range []string{"largest", "smallest"} {
+			i := largest
+			if extreme == "smallest" {
+				i = smallest
+			}
+			from := uint64(0)
+			if i > 0 {
+				from = nodes[i-1].Name
+			}
+			to := uint64(math.MaxUint64)
+			if i < len(nodes) {
+				to = nodes[i].Name
+			}
+			printRow(spacingBy, extreme, nameStr(from), nameStr(to), fmt.Sprint(spacings[i]))
+		}
+	}
+}
+
+func reportAutocorrelation(nodes []Node) {
+	// nodes must be sorted by name
+	stored := getAllStored(nodes)
+	fmt.Println()
+	printHeader("lag", "autocorrelation of "+storageUnits+" stored")
+	for lag := 1; lag <= autocorrelationLags && lag < len(stored); lag++ {
+		printRow(fmt.Sprint(lag), formatFloat(stats.Autocorrelation(stored, lag)))
+	}
+	if len(stored) > 1 {
+		printMetric("Autocorrelation of "+storageUnits+" stored with the next vault", stats.Autocorrelation(stored, 1))
+	}
+}
+
+func reportCoMembership(nodes []Node, ideal []Node) {
+	shared := getCoMembership(nodes)
+	pairs := [][2]uint64{}
+	for pair, _ := range shared {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if shared[pairs[i]] != shared[pairs[j]] {
+			return shared[pairs[i]] > shared[pairs[j]]
+		}
+		return pairs[i][0] < pairs[j][0] || (pairs[i][0] == pairs[j][0] && pairs[i][1] < pairs[j][1])
+	})
+	fmt.Println()
+	printHeader("vault", "vault", "expected share held by both")
+	for i, pair := range pairs {
+		if i >= coMembershipPairs {
+			break
+		}
+		printRow(nameStr(pair[0]), nameStr(pair[1]), formatFloat(shared[pair]))
+	}
+	most := 0.0
+	if len(pairs) > 0 {
+		most = shared[pairs[0]]
+	}
+	idealMost := 0.0
+	for _, share := range getCoMembership(ideal) {
+		idealMost = math.Max(idealMost, share)
+	}
+	printMetricWithIdeal("Largest expected share held by the same pair of vaults", most, idealMost)
+}
+
+func getCoMembership(nodes []Node) map[[2]uint64]float64 {
+	// Nodes must be sorted by name. Returns the fraction of chunk names for
+	// which both vaults of a pair are in the close group, for every pair
+	// that shares any, in the sections used by setExpectedShares.
+	bits := sectionPrefixBits
+	totalSections := uint64(1) << bits
+	shared := map[[2]uint64]float64{}
+	for section := uint64(0); section < totalSections; section++ {
+		prefix := section << (64 - bits)
+		names := []uint64{}
+		for _, n := range nodes {
+			if samePrefix(n.Name, prefix, bits) && !n.Elder {
+				names = append(names, n.Name)
+			}
+		}
+		addCoMembership(names, []uint64{}, bits, groupSize, 1/float64(totalSections), shared)
+	}
+	return shared
+}
+
+func addCoMembership(names []uint64, closer []uint64, depth uint, count int, weight float64, shared map[[2]uint64]float64) {
+	// Adds weight to every pair in the close group of chunk names as in
+	// addResponsibility. closer are the names already known to be in the
+	// group, and count more come from names.
+	if len(names) <= count {
+		group := append(append([]uint64{}, closer...), names...)
+		for i, a := range group {
+			for _, b := range group[i+1:] {
+				pair := [2]uint64{a, b}
+				if a > b {
+					pair = [2]uint64{b, a}
+				}
+				shared[pair] += weight
+			}
+		}
+		return
+	}
+	split := sort.Search(len(names), func(i int) bool {
+		return names[i]>>(63-depth)&1 == 1
+	})
+	halves := [][]uint64{names[0:split], names[split:]}
+	for b := 0; b < 2; b++ {
+		same := halves[b]
+		if len(same) >= count {
+			addCoMembership(same, closer, depth+1, count, weight/2, shared)
+			continue
+		}
+		nowCloser := append(append([]uint64{}, closer...), same...)
+		addCoMembership(halves[1-b], nowCloser, depth+1, count-len(same), weight/2, shared)
+	}
+}
+
+func nearestXorDistances(nodes []Node) []uint64 {
+	// The xor distance from each vault to its nearest vault, for nodes
+	// sorted by name. Moving away from a name in sorted order never
+	// lengthens the common prefix, so the search stops once the highest
+	// bit of the distance is above that of the nearest so far.
+	distances := []uint64{}
+	for i, n := range nodes {
+		nearest := uint64(math.MaxUint64)
+		for j := i - 1; j >= 0; j-- {
+			d := n.Name ^ nodes[j].Name
+			if bits.Len64(d) > bits.Len64(nearest) {
+				break
+			}
+			if d < nearest {
+				nearest = d
+			}
+		}
+		for j := i + 1; j < len(nodes); j++ {
+			d := n.Name ^ nodes[j].Name
+			if bits.Len64(d) > bits.Len64(nearest) {
+				break
+			}
+			if d < nearest {
+				nearest = d
+			}
+		}
+		distances = append(distances, nearest)
+	}
+	return distances
+}
+
+func getAllSpacings(nodes []Node) []uint64 {
+	return spacingsBy(nodes, spacingStrategy)
+}
+
+func spacingFor(spacingBy string) strategy.SpacingFunc {
+	// spacing strategies are checked before anything runs, so anything
+	// else is linear
+	spacing, err := strategy.Spacing(spacingBy)
+	if err != nil {
+		return strategy.LinearSpacing
+	}
+	return spacing
+}
+
+func spacingsBy(nodes []Node, spacingBy string) []uint64 {
+	spacing := spacingFor(spacingBy)
+	spacings := []uint64{}
+	// spacing from 0 to first name
+	firstSpacing := spacing(nodes[0].Name, 0)
+	spacings = append(spacings, firstSpacing)
+	// all other spacing between names
+	for i, _ := range nodes {
+		if i == 0 {
+			continue
+		}
+		spacings = append(spacings, spacing(nodes[i].Name, nodes[i-1].Name))
+	}
+	// spacing from last name to MaxUint64
+	lastName := nodes[len(nodes)-1].Name
+	lastSpacing := spacing(math.MaxUint64, lastName)
+	spacings = append(spacings, lastSpacing)
+	return spacings
+}
+
+func vaultHeader() string {
+	header := "vault name," + storageUnits + " stored,keyspace share,expected share,stored beyond expected"
+	for _, spacingBy := range spacingStrategies {
+		header += "," + spacingBy + " gap"
+	}
+	if *roles {
+		header += ",primary stored,replica stored"
+	}
+	if sampleScale > 0 {
+		header += ",sampling error"
+	}
+	if *chunksPerFile > 0 {
+		header += ",metadata stored"
+	}
+	if consensusModel != "none" {
+		header += ",messages"
+	}
+	if getPhaseRequests > 0 {
+		header += ",cold stored"
+	}
+	return header
+}
+
+func printVault(n Node, share float64) {
+	printRow(vaultRow(n, share)...)
+}
+
+func vaultRow(n Node, share float64) []string {
+	row := []string{nameStr(n.Name)}
+	if storageUnits == "chunks" && sampleScale == 0 {
+		// exact counts
+		row = append(row, fmt.Sprintf("%d", n.Chunks))
+	} else {
+		row = append(row, formatFloat(n.Stored))
+	}
+	row = append(row, formatFloat(share))
+	row = append(row, formatFloat(n.ExpectedShare))
+	row = append(row, formatFloat(n.Stored-n.ExpectedStored))
+	for s, _ := range spacingStrategies {
+		gap := ""
+		if s < len(n.Gaps) {
+			gap = fmt.Sprint(n.Gaps[s])
+		}
+		row = append(row, gap)
+	}
+	if *roles {
+		row = append(row, formatFloat(n.PrimaryStored))
+		row = append(row, formatFloat(n.Stored-n.PrimaryStored))
+	}
+	if sampleScale > 0 {
+		row = append(row, formatFloat(samplingError(n.Stored)))
+	}
+	if *chunksPerFile > 0 {
+		row = append(row, formatFloat(n.MetadataStored))
+	}
+	if consensusModel != "none" {
+		row = append(row, fmt.Sprintf("%d", n.Messages))
+	}
+	if getPhaseRequests > 0 {
+		row = append(row, formatFloat(n.ColdStored))
+	}
+	return row
+}
+
+func printParam(name string, value interface{}) {
+	parameters = append(parameters, Metric{name, value, nil})
+	printRow(name, formatValue(value))
+}
+
+func formatValue(value interface{}) string {
+	// decimals only change how floats are printed
+	if f, ok := value.(float64); ok && *decimals >= 0 {
+		return formatFloat(f)
+	}
+	return fmt.Sprint(value)
+}
+
+func formatFloat(f float64) string {
+	// Go never uses the locale, so the separator is always .
+	if *decimals < 0 {
+		return fmt.Sprintf("%f", f)
+	}
+	return strconv.FormatFloat(f, 'f', *decimals, 64)
+}
+
+func printHeader(cells ...string) {
+	printRow(cells...)
+	if *format == "markdown" {
+		printRow(strings.Split(strings.Repeat("---,", len(cells)-1)+"---", ",")...)
+	}
+}
+
+func printRow(cells ...string) {
+	if *format == "markdown" {
+		fmt.Println("| " + strings.Join(cells, " | ") + " |")
+	} else if *format == "tsv" {
+		fmt.Println(strings.Join(cells, "\t"))
+	} else {
+		fmt.Println(strings.Join(cells, ","))
+	}
+}
+
+func printMetric(title string, value interface{}) {
+	// markdown collects the metrics into one table printed at the end
+	printMetricWithIdeal(title, value, nil)
+}
+
+func printMetricWithIdeal(title string, value interface{}, ideal interface{}) {
+	summaryMetrics = append(summaryMetrics, Metric{title, value, ideal})
+	if *format == "markdown" {
+		return
+	}
+	fmt.Println("\n" + title + ":")
+	fmt.Println(formatValue(value))
+	if ideal != nil {
+		fmt.Printf("(ideal %s)\n", formatValue(ideal))
+	}
+}
+
+func printMetrics() {
+	if *format != "markdown" || len(summaryMetrics) == 0 {
+		return
+	}
+	fmt.Println()
+	printHeader("metric", "value", "ideal")
+	for _, metric := range summaryMetrics {
+		ideal := ""
+		if metric.Ideal != nil {
+			ideal = formatValue(metric.Ideal)
+		}
+		printRow(metric.Name, formatValue(metric.Value), ideal)
+	}
+}
+
+func writeResults(dir string, nodes []Node, shares map[uint64]float64) error {
+	// nodes must be sorted by name.
+	err := writeJSONObject(filepath.Join(dir, "params.json"), parameters)
+	if err != nil {
+		return err
+	}
+	err = writeJSONObject(filepath.Join(dir, "summary.json"), summaryMetrics)
+	if err != nil {
+		return err
+	}
+	vaults, err := createResultsFile(filepath.Join(dir, "vaults.csv"))
+	if err != nil {
+		return err
+	}
+	defer vaults.Close()
+	fmt.Fprintln(vaults, vaultHeader())
+	for _, n := range nodes {
+		fmt.Fprintln(vaults, strings.Join(vaultRow(n, shares[n.Name]), ","))
+	}
+	spacings, err := createResultsFile(filepath.Join(dir, "spacings.csv"))
+	if err != nil {
+		return err
+	}
+	defer spacings.Close()
+	// the first spacing starts at 0 and the last ends at the top of the
+	// namespace
+	fmt.Fprintln(spacings, "from,to,"+strings.Join(spacingStrategies, " spacing,")+" spacing")
+	from := uint64(0)
+	for i := 0; i <= len(nodes); i++ {
+		to := uint64(math.MaxUint64)
+		if i < len(nodes) {
+			to = nodes[i].Name
+		}
+		fmt.Fprintf(spacings, "%s,%s", nameStr(from), nameStr(to))
+		for _, spacingBy := range spacingStrategies {
+			fmt.Fprintf(spacings, ",%d", spacingFor(spacingBy)(to, from))
+		}
+		fmt.Fprintln(spacings)
+		from = to
+	}
+	if *plotScript {
+		script, err := createResultsFile(filepath.Join(dir, "plot.py"))
+		if err != nil {
+			return err
+		}
+		defer script.Close()
+		fmt.Fprint(script, plotPy)
+	}
+	return nil
+}
+
+// plotPy draws charts from the files written to outdir, saved as png next
+// to them. Columns are found by position since their names depend on
+// storageUnits.
+const plotPy = `#!/usr/bin/env python3
+# Charts for a simulate_chunks_in_vaults.go run, drawn from vaults.csv and
+# spacings.csv in the same directory. Run with python3 plot.py
+import csv
+import os
+
+import matplotlib
+matplotlib.use("Agg")
+import matplotlib.pyplot as plt
+
+here = os.path.dirname(os.path.abspath(__file__))
+
+
+def read(filename):
+    with open(os.path.join(here, filename)) as f:
+        rows = list(csv.reader(f))
+    return rows[0], rows[1:]
+
+
+def save(fig, filename):
+    fig.tight_layout()
+    fig.savefig(os.path.join(here, filename))
+    plt.close(fig)
+    print("wrote " + filename)
+
+
+# vaults are in name order
+header, vaults = read("vaults.csv")
+stored = [float(row[1]) for row in vaults]
+shares = [float(row[2]) for row in vaults]
+average = sum(stored) / len(stored)
+
+fig, ax = plt.subplots()
+ax.bar(range(len(stored)), stored, width=1.0)
+ax.axhline(average, color="red", label="average")
+ax.set_xlabel("vault, in name order")
+ax.set_ylabel(header[1])
+ax.legend()
+save(fig, "stored.png")
+
+fig, ax = plt.subplots()
+ax.hist(stored, bins=30)
+ax.set_xlabel(header[1])
+ax.set_ylabel("vaults")
+save(fig, "stored_histogram.png")
+
+fig, ax = plt.subplots()
+ax.scatter(shares, stored, s=8)
+ax.set_xlabel(header[2])
+ax.set_ylabel(header[1])
+save(fig, "stored_by_share.png")
+
+# one column of spacings per spacing strategy
+header, spacings = read("spacings.csv")
+fig, ax = plt.subplots()
+for column in range(2, len(header)):
+    values = [float(row[column]) for row in spacings]
+    ax.hist(values, bins=30, alpha=0.5, label=header[column])
+ax.set_xlabel("spacing")
+ax.set_ylabel("spacings")
+ax.legend()
+save(fig, "spacings.png")
+`
+
+func writeParquetVaults(nodes []Node, seed int64) error {
+	// multi-run modes only work out shares and gaps when writing them
+	if parquetVaults == nil {
+		return nil
+	}
+	sort.Sort(ByNodeName(nodes))
+	shares := map[uint64]float64{}
+	for i, share := range getKeyspaceShares(nodes) {
+		shares[nodes[i].Name] = share
+	}
+	setExpectedShares(nodes)
+	setGaps(nodes)
+	return parquetVaults.WriteVaults(nodes, shares, seed)
+}
+
+func closeParquetVaults() error {
+	// safe to call twice, so an early return can still close the file
+	if parquetVaults == nil {
+		return nil
+	}
+	err := parquetVaults.Close()
+	parquetVaults = nil
+	return err
+}
+
+func writeTidy(filename string, nodes []Node, shares map[uint64]float64) error {
+	// long format, parameters first then the usual vault columns
+	f, err := createResultsFile(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+	header := []string{}
+	values := []string{}
+	for _, param := range parameters {
+		header = append(header, param.Name)
+		values = append(values, formatValue(param.Value))
+	}
+	w.Write(append(header, strings.Split(vaultHeader(), ",")...))
+	for _, n := range nodes {
+		row := append([]string{}, values...)
+		w.Write(append(row, vaultRow(n, shares[n.Name])...))
+	}
+	return nil
+}
+
+func createResultsFile(filename string) (*os.File, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, errors.New("Cannot create results: " + err.Error())
+	}
+	return f, nil
+}
+
+func writeJSONObject(filename string, metrics []Metric) error {
+	// keys keep the order they were reported in
+	f, err := createResultsFile(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprint(f, "{")
+	for i, metric := range metrics {
+		if i > 0 {
+			fmt.Fprint(f, ",")
+		}
+		key, _ := json.Marshal(metric.Name)
+		value, err := json.Marshal(metric.Value)
+		if err != nil {
+			// eg an infinite ratio when a vault stores nothing
+			value = []byte("null")
+		}
+		fmt.Fprintf(f, "\n  %s: %s", key, value)
+	}
+	fmt.Fprintln(f, "\n}")
+	return nil
+}
+
+func idealNodes(n int) []Node {
+	// perfectly uniform names, as given by the uniform naming strategy
+	nodes := []Node{}
+	for i := 0; i < n; i++ {
+		progress := float64(i) / float64(n)
+		nodes = append(nodes, Node{Name: uint64(float64(math.MaxUint64) * progress)})
+	}
+	return nodes
+}
+
+func getKeyspaceShares(nodes []Node) []float64 {
+	// nodes must be sorted by name.
+	// Each vault is nominally responsible for the names closer to it than to
+	// its neighbours, ie from halfway to the previous vault to halfway to the
+	// next vault. The first and last vaults extend to the ends of the
+	// address space.
+	shares := []float64{}
+	maxName := float64(math.MaxUint64)
+	for i, n := range nodes {
+		start := 0.0
+		if i > 0 {
+			start = (float64(nodes[i-1].Name) + float64(n.Name)) / 2
+		}
+		end := maxName
+		if i < len(nodes)-1 {
+			end = (float64(n.Name) + float64(nodes[i+1].Name)) / 2
+		}
+		shares = append(shares, (end-start)/maxName)
+	}
+	return shares
+}
+
+func setExpectedShares(nodes []Node) {
+	// Nodes must be sorted by name. Chunks are stored by the closest
+	// groupSize adults in their section, so elders and each section are
+	// left out of the responsibility of the others.
+	bits := sectionPrefixBits
+	totalSections := uint64(1) << bits
+	responsibility := make([]float64, len(nodes))
+	total := 0.0
+	for section := uint64(0); section < totalSections; section++ {
+		prefix := section << (64 - bits)
+		names := []uint64{}
+		indexes := []int{}
+		for i, n := range nodes {
+			if samePrefix(n.Name, prefix, bits) && !n.Elder {
+				names = append(names, n.Name)
+				indexes = append(indexes, i)
+			}
+		}
+		held := make([]float64, len(names))
+		addResponsibility(names, held, bits, groupSize, 1/float64(totalSections))
+		for j, i := range indexes {
+			responsibility[i] = held[j]
+			total += held[j]
+		}
+	}
+	stored := stats.SumFloat(getAllStored(nodes))
+	for i, _ := range nodes {
+		nodes[i].ExpectedShare = responsibility[i] / total
+		nodes[i].ExpectedStored = nodes[i].ExpectedShare * stored
+	}
+}
+
+func setGaps(nodes []Node) {
+	// nodes must be sorted by name, and the first and last gaps run to the
+	// ends of the namespace as in spacings.csv
+	for s, spacingBy := range spacingStrategies {
+		spacings := spacingsBy(nodes, spacingBy)
+		for i, _ := range nodes {
+			if s == 0 {
+				nodes[i].Gaps = make([]uint64, len(spacingStrategies))
+			}
+			nodes[i].Gaps[s] = spacings[i] + spacings[i+1]
+		}
+	}
+}
+
+func addResponsibility(names []uint64, held []float64, depth uint, count int, weight float64) {
+	// Adds the fraction of chunk names for which each of the sorted names
+	// is one of the count closest. Chunk names make up weight of the
+	// namespace and share depth leading bits, and names all share depth
+	// leading bits, though not necessarily the same as the chunks.
+	if len(names) <= count {
+		for i, _ := range held {
+			held[i] += weight
+		}
+		return
+	}
+	split := sort.Search(len(names), func(i int) bool {
+		return names[i]>>(63-depth)&1 == 1
+	})
+	halves := [][]uint64{names[0:split], names[split:]}
+	heldHalves := [][]float64{held[0:split], held[split:]}
+	// half the chunks have each value of the next bit, and are closest to
+	// the names with the same value for it
+	for b := 0; b < 2; b++ {
+		same := halves[b]
+		if len(same) >= count {
+			addResponsibility(same, heldHalves[b], depth+1, count, weight/2)
+			continue
+		}
+		for i, _ := range heldHalves[b] {
+			heldHalves[b][i] += weight / 2
+		}
+		addResponsibility(halves[1-b], heldHalves[1-b], depth+1, count-len(same), weight/2)
+	}
+}
+
+func getRandomChunkSize() float64 {
+	// returns a chunk size in MB
+	i := rng.Float64()
+	for _, bucket := range chunkSizeBuckets {
+		if i < bucket.CumulativeProbability {
+			return rng.Float64()*0.1 + bucket.MinSize
+		}
+	}
+	// 1000+
+	return 1
+}
+
+func importLog(args []string) error {
+	// Writes the vault names found in sn_node logs or network map dumps as
+	// a names file for the names flag. Only full 64 hex character XorNames
+	// are used, since abbreviated names such as 8a3f12.. have lost most of
+	// their bits.
+	importFlags := flag.NewFlagSet("import-log", flag.ExitOnError)
+	logFile := importFlags.String("log", "", "sn_node log or network map "+
+		"dump to read vault names from")
+	out := importFlags.String("out", "", "names file to write, one hex name "+
+		"per line")
+	pattern := importFlags.String("pattern", "", "regular expression for "+
+		"lines naming a vault, where the first group is the name, eg "+
+		"'name: ([0-9a-f]{64})' to skip chunk and section names, default "+
+		"every XorName in the log")
+	err := flagsFromEnvironment(importFlags, "SAFE_SIM_IMPORT_LOG_")
+	if err != nil {
+		return err
+	}
+	importFlags.Parse(args)
+	if *logFile == "" || *out == "" {
+		return ParameterError("import-log needs --log sn_node.log and --out names.txt")
+	}
+	re := xorNamePattern
+	if *pattern != "" {
+		re, err = regexp.Compile(*pattern)
+		if err != nil {
+			return ParameterError("Invalid pattern: " + err.Error())
+		}
+	}
+	f, err := os.Open(*logFile)
+	if err != nil {
+		return errors.New("Cannot open log: " + err.Error())
+	}
+	defer f.Close()
+	names, err := xorNamesInLog(f, re)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return ParameterError("No vault names in " + *logFile)
+	}
+	w, err := os.Create(*out)
+	if err != nil {
+		return errors.New("Cannot create names: " + err.Error())
+	}
+	defer w.Close()
+	for _, name := range names {
+		fmt.Fprintln(w, name)
+	}
+	fmt.Printf("\n%d vault names written to %s\n", len(names), *out)
+	return nil
+}
+
+// xorNamePattern matches a full 256 bit XorName written as hex
+var xorNamePattern = regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`)
+
+func xorNamesInLog(r io.Reader, re *regexp.Regexp) ([]string, error) {
+	// names in the order first seen, each once, from the first group of
+	// the pattern or the whole match if it has no groups
+	names := []string{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	// log lines listing a whole section can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		for _, match := range re.FindAllStringSubmatch(scanner.Text(), -1) {
+			name := match[0]
+			if len(match) > 1 {
+				name = match[1]
+			}
+			name = strings.ToLower(name)
+			if _, err := parseName(name); err != nil || name == "" {
+				continue
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read log: " + err.Error())
+	}
+	return names, nil
+}
+
+func fitSizes(args []string) error {
+	// writes the chunk size distribution of observed traffic sizes in the
+	// format read by the chunk-sizes flag
+	fitFlags := flag.NewFlagSet("fit-sizes", flag.ExitOnError)
+	sizesFile := fitFlags.String("sizes", "", "csv of observed sizes in "+
+		"bytes, one per line in the first column")
+	out := fitFlags.String("out", "", "csv file to write the distribution "+
+		"to, for the chunk-sizes flag")
+	err := flagsFromEnvironment(fitFlags, "SAFE_SIM_FIT_SIZES_")
+	if err != nil {
+		return err
+	}
+	fitFlags.Parse(args)
+	if *sizesFile == "" || *out == "" {
+		return ParameterError("fit-sizes needs --sizes sizes.csv and --out chunksizes.csv")
+	}
+	sizes, err := readSizes(*sizesFile)
+	if err != nil {
+		return err
+	}
+	if len(sizes) == 0 {
+		return ParameterError("No sizes in " + *sizesFile)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return errors.New("Cannot create chunk sizes: " + err.Error())
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	fmt.Fprintln(w, "cumulative probability,min size")
+	for _, bucket := range fitChunkSizeBuckets(sizes) {
+		fmt.Fprintf(w, "%f,%.1f\n", bucket.CumulativeProbability, bucket.MinSize)
+	}
+	return nil
+}
+
+func readSizes(filename string) ([]float64, error) {
+	// sizes in MB from bytes in the first column, with an optional header
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("Cannot open sizes: " + err.Error())
+	}
+	defer f.Close()
+	sizes := []float64{}
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(strings.Split(line, ",")[0]), 64)
+		if err != nil || value < 0 {
+			if i == 0 && err != nil {
+				// header
+				continue
+			}
+			return nil, ParameterError("Invalid size: " + line)
+		}
+		sizes = append(sizes, value/1000000)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read sizes: " + err.Error())
+	}
+	return sizes, nil
+}
+
+func fitChunkSizeBuckets(sizes []float64) []ChunkSizeBucket {
+	// counts sizes in MB into buckets of 0.1 MB up to 1 MB, where anything
+	// larger is beyond the last bucket
+	counts := make([]int, 10)
+	for _, size := range sizes {
+		if size < 1 {
+			counts[int(size*10)] += 1
+		}
+	}
+	buckets := []ChunkSizeBucket{}
+	cumulative := 0
+	for i, count := range counts {
+		cumulative += count
+		buckets = append(buckets, ChunkSizeBucket{
+			CumulativeProbability: float64(cumulative) / float64(len(sizes)),
+			MinSize:               float64(i) / 10,
+		})
+	}
+	return buckets
+}
+
+func readChunkSizeBuckets(filename string) ([]ChunkSizeBucket, error) {
+	// lines of cumulative probability,min size with an optional header line
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("Cannot open chunk sizes: " + err.Error())
+	}
+	defer f.Close()
+	buckets := []ChunkSizeBucket{}
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) < 2 {
+			return nil, ParameterError("Invalid chunk sizes line: " + line)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil && i == 0 {
+			// header
+			continue
+		}
+		minSize, sizeErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || sizeErr != nil {
+			return nil, ParameterError("Invalid chunk sizes line: " + line)
+		}
+		// probabilities and sizes only go up, and sizes stay below 1 MB
+		// so chunks never exceed the maximum chunk size
+		if p < 0 || p > 1 || minSize < 0 || minSize > 0.9 {
+			return nil, ParameterError("Invalid chunk sizes line: " + line)
+		}
+		if len(buckets) > 0 {
+			previous := buckets[len(buckets)-1]
+			if p < previous.CumulativeProbability || minSize <= previous.MinSize {
+				return nil, ParameterError("Chunk sizes must be in order: " + line)
+			}
+		}
+		buckets = append(buckets, ChunkSizeBucket{p, minSize})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Cannot read chunk sizes: " + err.Error())
+	}
+	if len(buckets) == 0 {
+		return nil, ParameterError("No chunk sizes in " + filename)
+	}
+	return buckets, nil
+}
+
+func chunkSizeMoments() (float64, float64) {
+	// returns the mean and mean square of getRandomChunkSize in MB
+	mean := 0.0
+	meanSquare := 0.0
+	previous := 0.0
+	for _, bucket := range chunkSizeBuckets {
+		p := bucket.CumulativeProbability - previous
+		// sizes are spread evenly from MinSize to MinSize + 0.1
+		a := bucket.MinSize
+		mean += p * (a + 0.05)
+		meanSquare += p * (a*a + 0.1*a + 0.01/3)
+		previous = bucket.CumulativeProbability
+	}
+	// 1000+ is always 1 MB
+	mean += 1 - previous
+	meanSquare += 1 - previous
+	return mean, meanSquare
+}
@@ -0,0 +1,147 @@
+//go:build !js
+
+package sim
+
+// gRPC API for the serve subcommand, so runs can be scheduled on a large
+// machine from a laptop and their results streamed back as they finish. The
+// service is defined in proto/simulation.proto, eg
+//
+//   grpcurl -plaintext -import-path proto -proto simulation.proto \
+//     -d '{"naming_strategy": "random"}' localhost:8081 safesim.Simulation/SubmitRun
+//
+// Not in the browser build, which can't listen for connections.
+
+//go:generate protoc -I../.. --go_out=../.. --go_opt=module=github.com/iancoleman/safe_chunk_responsibility_simulation --go-grpc_out=../.. --go-grpc_opt=module=github.com/iancoleman/safe_chunk_responsibility_simulation ../../proto/simulation.proto
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/iancoleman/safe_chunk_responsibility_simulation/pkg/simpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type simulationServer struct {
+	simpb.UnimplementedSimulationServer
+	jobs *JobQueue
+}
+
+func init() {
+	serveGRPC = serveSimulationGRPC
+}
+
+func serveSimulationGRPC(ctx context.Context, addr string, jobs *JobQueue) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.New("Cannot serve gRPC: " + err.Error())
+	}
+	server := grpc.NewServer()
+	simpb.RegisterSimulationServer(server, &simulationServer{jobs: jobs})
+	go func() {
+		// Stop rather than GracefulStop, which would wait for every
+		// GetResults stream to end
+		<-ctx.Done()
+		server.Stop()
+	}()
+	err = server.Serve(listener)
+	if err != nil {
+		return errors.New("Cannot serve gRPC: " + err.Error())
+	}
+	return nil
+}
+
+func (s *simulationServer) SubmitRun(ctx context.Context, params *simpb.RunParams) (*simpb.Job, error) {
+	job, err := s.jobs.Submit(func(p *RunParams) error {
+		setRunParams(p, params)
+		return nil
+	})
+	if err == errQueueFull {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return jobMessage(job), nil
+}
+
+func (s *simulationServer) GetStatus(ctx context.Context, id *simpb.JobId) (*simpb.Job, error) {
+	job, ok := s.jobs.Get(int(id.GetId()))
+	if !ok {
+		return nil, status.Error(codes.NotFound, "No such run")
+	}
+	return jobMessage(job), nil
+}
+
+func (s *simulationServer) GetResults(ids *simpb.JobIds, stream grpc.ServerStreamingServer[simpb.Job]) error {
+	wanted := []int{}
+	for _, id := range ids.GetIds() {
+		wanted = append(wanted, int(id))
+	}
+	err := s.jobs.Wait(stream.Context(), wanted, func(job Job) error {
+		return stream.Send(jobMessage(job))
+	})
+	if _, ok := err.(ParameterError); ok {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func setRunParams(p *RunParams, m *simpb.RunParams) {
+	// only the parameters that were set replace the defaults
+	if m.Seed != nil {
+		p.Seed = *m.Seed
+	}
+	if m.TotalNodes != nil {
+		p.TotalNodes = int(*m.TotalNodes)
+	}
+	if m.TotalStored != nil {
+		p.TotalStored = int(*m.TotalStored)
+	}
+	if m.GroupSize != nil {
+		p.GroupSize = int(*m.GroupSize)
+	}
+	if m.NamingStrategy != nil {
+		p.NamingStrategy = *m.NamingStrategy
+	}
+	if m.BestFitDivisor != nil {
+		p.BestFitDivisor = *m.BestFitDivisor
+	}
+	if m.QuietestDepth != nil {
+		p.QuietestDepth = uint(*m.QuietestDepth)
+	}
+	if m.SpacingStrategy != nil {
+		p.SpacingStrategy = *m.SpacingStrategy
+	}
+}
+
+func jobMessage(job Job) *simpb.Job {
+	m := &simpb.Job{
+		Id:     int32(job.ID),
+		Status: job.Status,
+		Params: &simpb.RunParams{
+			Seed:            proto.Int64(job.Params.Seed),
+			TotalNodes:      proto.Int32(int32(job.Params.TotalNodes)),
+			TotalStored:     proto.Int32(int32(job.Params.TotalStored)),
+			GroupSize:       proto.Int32(int32(job.Params.GroupSize)),
+			NamingStrategy:  proto.String(job.Params.NamingStrategy),
+			BestFitDivisor:  proto.Uint64(job.Params.BestFitDivisor),
+			QuietestDepth:   proto.Uint32(uint32(job.Params.QuietestDepth)),
+			SpacingStrategy: proto.String(job.Params.SpacingStrategy),
+		},
+	}
+	if job.Result != nil {
+		m.Result = &simpb.RunResult{
+			Error:                     job.Result.Error,
+			StandardDeviation:         job.Result.StandardDeviation,
+			RelativeStandardDeviation: job.Result.RelativeStandardDeviation,
+			Gini:                      job.Result.Gini,
+			MaxOverAverage:            job.Result.MaxOverAverage,
+			MinOverAverage:            job.Result.MinOverAverage,
+		}
+	}
+	return m
+}
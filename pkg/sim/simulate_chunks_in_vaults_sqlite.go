@@ -0,0 +1,144 @@
+//go:build !js
+
+package sim
+
+// SQLite output, so hundreds of runs can be queried with SQL. Every run
+// appended to the same database gets a new id in the runs table, which the
+// params, metrics and vaults tables refer to, eg
+//
+//   SELECT p.value, AVG(v.stored) FROM vaults v
+//   JOIN params p ON p.run = v.run AND p.name = 'namingStrategy'
+//   GROUP BY p.value
+//
+// Not in the browser build, which has no file system.
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	appendToDatabase = appendRunToSQLite
+}
+
+func sqliteSchema() []string {
+	vaultColumns := "run INTEGER NOT NULL REFERENCES runs(id), name TEXT NOT NULL, " +
+		"stored REAL, chunks INTEGER, keyspace_share REAL, expected_share REAL, " +
+		"stored_beyond_expected REAL, primary_stored REAL, metadata_stored REAL, " +
+		"cold_stored REAL, messages INTEGER"
+	for _, spacingBy := range spacingStrategies {
+		vaultColumns += ", " + spacingBy + "_gap REAL"
+	}
+	return []string{
+		"CREATE TABLE IF NOT EXISTS runs (id INTEGER PRIMARY KEY, created TEXT NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS params (run INTEGER NOT NULL REFERENCES runs(id), name TEXT NOT NULL, value)",
+		"CREATE TABLE IF NOT EXISTS metrics (run INTEGER NOT NULL REFERENCES runs(id), name TEXT NOT NULL, value, ideal)",
+		"CREATE TABLE IF NOT EXISTS vaults (" + vaultColumns + ")",
+	}
+}
+
+func appendRunToSQLite(filename string, nodes []Node, shares map[uint64]float64) error {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return errors.New("Cannot open database: " + err.Error())
+	}
+	defer db.Close()
+	for _, statement := range sqliteSchema() {
+		_, err = db.Exec(statement)
+		if err != nil {
+			return errors.New("Cannot create database tables: " + err.Error())
+		}
+	}
+	// one transaction per run, which is much faster than one per row and
+	// never leaves half a run in the database
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.New("Cannot write database: " + err.Error())
+	}
+	err = insertRun(tx, nodes, shares)
+	if err != nil {
+		tx.Rollback()
+		return errors.New("Cannot write database: " + err.Error())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("Cannot write database: " + err.Error())
+	}
+	return nil
+}
+
+func insertRun(tx *sql.Tx, nodes []Node, shares map[uint64]float64) error {
+	result, err := tx.Exec("INSERT INTO runs (created) VALUES (?)", time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	run, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, param := range parameters {
+		_, err = tx.Exec("INSERT INTO params (run, name, value) VALUES (?, ?, ?)", run, param.Name, sqlValue(param.Value))
+		if err != nil {
+			return err
+		}
+	}
+	for _, metric := range summaryMetrics {
+		_, err = tx.Exec("INSERT INTO metrics (run, name, value, ideal) VALUES (?, ?, ?, ?)", run, metric.Name, sqlValue(metric.Value), sqlValue(metric.Ideal))
+		if err != nil {
+			return err
+		}
+	}
+	insert := "INSERT INTO vaults VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?"
+	for range spacingStrategies {
+		insert += ", ?"
+	}
+	vault, err := tx.Prepare(insert + ")")
+	if err != nil {
+		return err
+	}
+	defer vault.Close()
+	for _, n := range nodes {
+		row := []interface{}{run, nameStr(n.Name), n.Stored, sqlValue(n.Chunks), shares[n.Name],
+			n.ExpectedShare, n.Stored - n.ExpectedStored, n.PrimaryStored, n.MetadataStored,
+			n.ColdStored, n.Messages}
+		for s, _ := range spacingStrategies {
+			var gap interface{} = nil
+			if s < len(n.Gaps) {
+				gap = float64(n.Gaps[s])
+			}
+			row = append(row, gap)
+		}
+		_, err = vault.Exec(row...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sqlValue(value interface{}) interface{} {
+	// SQLite integers are signed 64 bit and it has no infinity, so large
+	// names and spacings are stored as reals and infinite ratios as null
+	switch v := value.(type) {
+	case nil, bool, int, int64, string:
+		return v
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil
+		}
+		return v
+	case uint64:
+		if v > math.MaxInt64 {
+			return float64(v)
+		}
+		return int64(v)
+	case uint:
+		return sqlValue(uint64(v))
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,362 @@
+package sim
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func withGroupSize(size int) func() {
+	// sets groupSize until the returned func restores the flag value
+	flagGroupSize := groupSize
+	groupSize = size
+	return func() {
+		groupSize = flagGroupSize
+	}
+}
+
+func TestSameSeedSameRun(t *testing.T) {
+	// a run must not depend on the runs before it in the same process
+	params := defaultRunParams(7)
+	params.TotalStored = 10000
+	other := params
+	other.Seed = 8
+	first, firstNodes := simulateWithParams(context.Background(), params)
+	simulateWithParams(context.Background(), other)
+	again, againNodes := simulateWithParams(context.Background(), params)
+	if first.Error != "" {
+		t.Fatal(first.Error)
+	}
+	if !reflect.DeepEqual(first, again) || !reflect.DeepEqual(firstNodes, againNodes) {
+		t.Error("Same seed gave a different run after another run")
+	}
+}
+
+func TestSubsectionTotals(t *testing.T) {
+	nodes := []Node{
+		{Name: 0x0000000000000001, Stored: 1},
+		{Name: 0x7FFFFFFFFFFFFFFF, Stored: 2},
+		{Name: 0x8000000000000000, Stored: 4},
+	}
+	vaults, totals := getSubsectionTotals(nodes, 1)
+	if vaults[0] != 2 || vaults[1] != 1 || totals[0] != 3 || totals[1] != 4 {
+		t.Error("Fail subsection totals")
+	}
+}
+
+func TestKeyspaceShares(t *testing.T) {
+	shares := getKeyspaceShares([]Node{
+		{Name: 0x4000000000000000},
+		{Name: 0x8000000000000000},
+	})
+	if shares[0] != 0.375 || shares[1] != 0.625 {
+		t.Error("Fail keyspace shares")
+	}
+}
+
+func TestFitChunkSizeBuckets(t *testing.T) {
+	// sizes in MB, where 2 MB is beyond the last bucket
+	fitted := fitChunkSizeBuckets([]float64{0.05, 0.05, 0.15, 2})
+	if len(fitted) != 10 || fitted[0].CumulativeProbability != 0.5 || fitted[1].CumulativeProbability != 0.75 || fitted[9].CumulativeProbability != 0.75 || fitted[1].MinSize != 0.1 {
+		t.Error("Fail fitting chunk sizes")
+	}
+}
+
+func TestXorNamesInLog(t *testing.T) {
+	// each name once, skipping abbreviated names
+	names, err := xorNamesInLog(strings.NewReader("peer 8a3f12.. joined\n"+
+		"members: ["+strings.Repeat("ab", 32)+", "+strings.Repeat("0c", 32)+"]\n"+
+		"relocated "+strings.Repeat("AB", 32)+"\n"), xorNamePattern)
+	if err != nil || len(names) != 2 || names[0] != strings.Repeat("ab", 32) {
+		t.Error("Fail vault names in log")
+	}
+}
+
+func TestNameIndex(t *testing.T) {
+	defer startRun(0)
+	startRun(0)
+	sortedNames([]Node{{Name: 0x9}, {Name: 0x3}})
+	indexedSubsectionVaults()
+	indexName(0x5)
+	indexName(0x8000000000000000)
+	unindexName(0x9)
+	unindexName(0x7)
+	if len(nameIndex) != 3 || nameIndex[0] != 0x3 || nameIndex[1] != 0x5 || !nameIsIndexed(0x5) || nameIsIndexed(0x9) {
+		t.Error("Fail sorted name index")
+	}
+	if quietestVaults[0] != 2 || quietestVaults[1] != 1 {
+		t.Error("Fail subsection counts of name index")
+	}
+}
+
+func TestSectionIndex(t *testing.T) {
+	if prefixStr(0x4000000000000000, 3) != "010" {
+		t.Error("Fail section prefix string")
+	}
+	sections := []Section{{Prefix: 0, Bits: 1}, {Prefix: 0x8000000000000000, Bits: 2}, {Prefix: 0xC000000000000000, Bits: 2}}
+	if sectionIndex(sections, 0x7FFFFFFFFFFFFFFF) != 0 || sectionIndex(sections, 0xBFFFFFFFFFFFFFFF) != 1 || sectionIndex(sections, math.MaxUint64) != 2 {
+		t.Error("Fail section of name")
+	}
+}
+
+func TestParseName(t *testing.T) {
+	parsed, err := parseName("0x00000000000000ff")
+	if err != nil || parsed != 0xFF {
+		t.Error("Fail parsing name")
+	}
+	parsed, err = parseName("A3000000000000001234")
+	if err != nil || parsed != 0xA300000000000000 {
+		t.Error("Fail parsing long name")
+	}
+}
+
+func TestExpectedResponsibility(t *testing.T) {
+	// the first two names share chunks starting with 0 and the third has
+	// every chunk starting with 1
+	held := make([]float64, 3)
+	addResponsibility([]uint64{0x0, 0x4000000000000000, 0x8000000000000000}, held, 0, 2, 1)
+	if held[0] != 0.75 || held[1] != 0.75 || held[2] != 0.5 {
+		t.Error("Fail expected responsibility")
+	}
+}
+
+func TestExpectedStored(t *testing.T) {
+	// the third vault is expected to store a quarter of the copies but
+	// stores none
+	defer withGroupSize(2)()
+	nodes := []Node{
+		{Name: 0x0, Stored: 4},
+		{Name: 0x4000000000000000, Stored: 4},
+		{Name: 0x8000000000000000},
+	}
+	setExpectedShares(nodes)
+	if nodes[0].ExpectedStored != 3 || nodes[2].ExpectedStored != 2 {
+		t.Error("Fail expected stored")
+	}
+}
+
+func TestCloseGroupChunks(t *testing.T) {
+	// 0x4 is one of the closest two to the first chunk but not the second
+	defer withGroupSize(2)()
+	nodes := []Node{{Name: 0x0}, {Name: 0x4}, {Name: 0x8}, {Name: 0xC}}
+	inGroup := closeGroupChunks(nodes, []Chunk{{Name: 0x1}, {Name: 0xE}}, nodes[1])
+	if len(inGroup) != 1 || inGroup[0] != 0 {
+		t.Error("Fail chunks in close group")
+	}
+}
+
+func TestEventsUntilGroupsChange(t *testing.T) {
+	// the last chunk never changed close group
+	defer startRun(0)
+	groupFirstChanged = []int{3, 1, 2, 0}
+	if eventsUntilGroupsChange(0.5) != 2 || eventsUntilGroupsChange(1) != 0 {
+		t.Error("Fail events until close groups change")
+	}
+}
+
+func TestDistinctGroups(t *testing.T) {
+	// the first two chunks have the same holders in a different order
+	groups := distinctGroups([]Chunk{
+		{Holders: []uint64{0x1, 0x2}},
+		{Holders: []uint64{0x2, 0x1}},
+		{Holders: []uint64{0x2, 0x3}},
+	})
+	if len(groups) != 2 || anyGroupFailed(groups, map[uint64]bool{0x1: true, 0x3: true}) || !anyGroupFailed(groups, map[uint64]bool{0x2: true, 0x3: true}) {
+		t.Error("Fail distinct groups")
+	}
+}
+
+func TestSharesConverged(t *testing.T) {
+	// the first check has nothing to compare, the second changes no share
+	// and the third moves one by more than the threshold
+	defer startRun(0)
+	startRun(0)
+	nodes := []Node{{Name: 0x1}, {Name: 0x2}}
+	empty := sharesConverged(nodes)
+	nodes[0].Stored, nodes[1].Stored = 1, 3
+	first := sharesConverged(nodes)
+	nodes[0].Stored, nodes[1].Stored = 2, 6
+	second := sharesConverged(nodes)
+	nodes[0].Stored = 4
+	third := sharesConverged(nodes)
+	if empty || first || !second || third {
+		t.Error("Fail shares converged")
+	}
+}
+
+func TestHandoff(t *testing.T) {
+	// the departing vault passes its copy to the closest vault not holding
+	// one and the other holder keeps its copy
+	nodes := []Node{{Name: 0x0, Stored: 1}, {Name: 0x4, Stored: 1}, {Name: 0x8}}
+	chunks := []Chunk{{Name: 0x1, Amount: 1, Holders: []uint64{0x0, 0x4}}}
+	holdings := map[uint64][]int{0x0: {0}, 0x4: {0}}
+	nodes = handOffChunks(nodes, 0, chunks, holdings)
+	if len(nodes) != 2 || !nameIsTaken(0x8, chunks[0].Holders) || len(chunks[0].Holders) != 2 || nodes[1].Stored != 1 {
+		t.Fatal("Fail handoff")
+	}
+	// a joining vault closer than the furthest holder takes its copy
+	defer withGroupSize(2)()
+	nodes = append(nodes, Node{Name: 0x0})
+	handOnChunks(nodes, 2, chunks, holdings)
+	if nameIsTaken(0x8, chunks[0].Holders) || !nameIsTaken(0x0, chunks[0].Holders) || nodes[1].Stored != 0 || nodes[2].Stored != 1 {
+		t.Error("Fail handoff to a joining vault")
+	}
+}
+
+func TestRouting(t *testing.T) {
+	// each vault only knows its nearest neighbour so a message from 0x1
+	// passes 0x2 on the way to 0x4
+	names := []uint64{0x1, 0x2, 0x4}
+	tables := buildRoutingTables(names, 1)
+	if len(tables[0]) != 2 {
+		t.Error("Fail routing tables")
+	}
+	hops, ok := routeHops(names, [][]int{{1}, {2}, {1}}, 0, 0x6, map[uint64]bool{0x4: true})
+	if !ok || hops != 2 {
+		t.Error("Fail routing hops")
+	}
+	// vaults with the same name are all in the same bucket
+	tables = buildRoutingTables([]uint64{0x1, 0x1, 0x3, 0x3, 0x3}, 2)
+	if len(tables[0]) != 2 || len(tables[2]) != 2 {
+		t.Error("Fail routing tables with duplicate names")
+	}
+}
+
+func TestGaps(t *testing.T) {
+	// from the previous name to the next
+	nodes := []Node{{Name: 0x1000000000000000}, {Name: 0x3000000000000000}}
+	setGaps(nodes)
+	if nodes[0].Gaps[0] != 0x3000000000000000 || nodes[1].Gaps[0] != math.MaxUint64-0x1000000000000000 {
+		t.Error("Fail gaps")
+	}
+}
+
+func TestFractionAbove(t *testing.T) {
+	if fractionAbove([]float64{0.01, 0.02, 0.03, 0.04}, 0.02) != 0.5 {
+		t.Error("Fail fraction above")
+	}
+}
+
+func TestCoMembership(t *testing.T) {
+	// the first two names share every chunk starting with 0, and the first
+	// and last share half of those starting with 1
+	shared := map[[2]uint64]float64{}
+	addCoMembership([]uint64{0x0, 0x4000000000000000, 0x8000000000000000}, []uint64{}, 0, 2, 1, shared)
+	if shared[[2]uint64{0x0, 0x4000000000000000}] != 0.5 || shared[[2]uint64{0x0, 0x8000000000000000}] != 0.25 {
+		t.Error("Fail co-membership")
+	}
+}
+
+func TestNearestXorDistances(t *testing.T) {
+	// 0x8 is nearest 0x1 even though 0x6 and 0x4 come between them in name
+	// order
+	nearest := nearestXorDistances([]Node{{Name: 0x1}, {Name: 0x4}, {Name: 0x6}, {Name: 0x8}})
+	if nearest[0] != 0x5 || nearest[1] != 0x2 || nearest[2] != 0x2 || nearest[3] != 0x9 {
+		t.Error("Fail nearest xor distance")
+	}
+}
+
+func TestPlacementBackends(t *testing.T) {
+	// closest vaults are the same from every placement backend
+	chosenBackend := placementBackend
+	defer func() {
+		placementBackend = chosenBackend
+	}()
+	r := rand.New(rand.NewSource(1))
+	nodes := []Node{}
+	for i := 0; i < 200; i++ {
+		nodes = append(nodes, Node{Name: r.Uint64()})
+	}
+	for _, count := range []int{0, 1, 8, 199, 200, 250} {
+		for i := 0; i < 20; i++ {
+			chunkName := r.Uint64()
+			groups := [][]Node{}
+			for _, backend := range placementBackends {
+				placementBackend = backend
+				group := closestNodes(nodes, chunkName, count)
+				groups = append(groups, append([]Node{}, group...))
+			}
+			for _, group := range groups[1:] {
+				if len(group) != len(groups[0]) {
+					t.Fatal("Fail closest vaults from placement backends")
+				}
+				for j, _ := range group {
+					if group[j].Name != groups[0][j].Name {
+						t.Fatal("Fail closest vaults from placement backends")
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyPlacement(t *testing.T) {
+	// 0x8 is not one of the closest two to 0x1
+	defer startRun(0)
+	startRun(0)
+	defer withGroupSize(2)()
+	nodes := []Node{{Name: 0x0}, {Name: 0x4}, {Name: 0x8}, {Name: 0xC}}
+	verifyPlacement(nodes, 0x1, []Node{{Name: 0x0}, {Name: 0x4}})
+	verifyPlacement(nodes, 0x1, []Node{{Name: 0x0}, {Name: 0x8}})
+	if placementsVerified != 2 || placementMismatches != 1 || firstMismatchChunk != 0x1 {
+		t.Error("Fail verifying placements")
+	}
+}
+
+func TestNameFormats(t *testing.T) {
+	if hexName(0xFF) != "00000000000000ff" {
+		t.Error("Fail hex name")
+	}
+	chosenFormat := *nameFormat
+	defer func() {
+		*nameFormat = chosenFormat
+	}()
+	*nameFormat = "base32"
+	if nameStr(0xFF) != "aaaaaaaaaaap6" {
+		t.Error("Fail base32 name")
+	}
+	*nameFormat = "binary"
+	if nameStr(0xFF) != strings.Repeat("0", 56)+"11111111" {
+		t.Error("Fail binary name")
+	}
+	*nameFormat = "xorname"
+	xorName := nameStr(0xA3000000000000FF)
+	parsed, err := parseName(xorName)
+	if len(xorName) != 64 || err != nil || parsed != 0xA3000000000000FF {
+		t.Error("Fail xorname name")
+	}
+}
+
+func TestReadChunkNames(t *testing.T) {
+	// skipping blank lines and comments
+	names, err := readChunkNames(strings.NewReader("# names\n8a\n\n01\n"))
+	if err != nil || len(names) != 2 || names[0] != 0x8A00000000000000 || names[1] != 0x0100000000000000 {
+		t.Error("Fail reading chunk names")
+	}
+}
+
+func TestParseHotspot(t *testing.T) {
+	prefix, bits, fraction, err := parseHotspot("a3,0.2")
+	if err != nil || prefix != 0xA300000000000000 || bits != 8 || fraction != 0.2 {
+		t.Error("Fail parsing hotspot")
+	}
+}
+
+func TestRunInProcessStderr(t *testing.T) {
+	// a failed run says why, from the stderr of its process
+	executable := filepath.Join(t.TempDir(), "failing")
+	script := "#!/bin/sh\necho groupSize is too big >&2\nexit 2\n"
+	err := os.WriteFile(executable, []byte(script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := runInProcess(context.Background(), executable, defaultRunParams(1))
+	if !strings.HasSuffix(result.Error, ": groupSize is too big") {
+		t.Error("Fail stderr of failed run: " + result.Error)
+	}
+}
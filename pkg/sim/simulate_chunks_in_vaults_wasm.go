@@ -0,0 +1,52 @@
+//go:build js && wasm
+
+package sim
+
+// The browser build, where the page calls simulate with a json object of
+// parameters and draws the result. Build it from the root of the module
+// with GOOS=js GOARCH=wasm go build -o web/simulate.wasm .
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"syscall/js"
+	"time"
+)
+
+// BrowserResult is a run for the page, with the amount stored by each vault
+// in name order for the chart.
+type BrowserResult struct {
+	RunResult
+	Stored []float64 `json:"stored"`
+}
+
+func init() {
+	serveBrowser = serveSimulate
+}
+
+func serveSimulate() {
+	js.Global().Set("simulate", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		params := defaultRunParams(time.Now().UnixNano())
+		if len(args) > 0 {
+			err := json.Unmarshal([]byte(args[0].String()), &params)
+			if err != nil {
+				return browserJSON(BrowserResult{RunResult: RunResult{Error: "Invalid parameters: " + err.Error()}})
+			}
+		}
+		result, nodes := simulateWithParams(context.Background(), params)
+		sort.Sort(ByNodeName(nodes))
+		return browserJSON(BrowserResult{RunResult: result, Stored: getAllStored(nodes)})
+	}))
+	// the page calls simulate for as long as it is open
+	select {}
+}
+
+func browserJSON(result BrowserResult) string {
+	b, err := json.Marshal(result)
+	if err != nil {
+		// eg an infinite ratio when a vault stores nothing
+		b, _ = json.Marshal(BrowserResult{RunResult: RunResult{Error: err.Error()}})
+	}
+	return string(b)
+}
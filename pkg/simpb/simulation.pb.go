@@ -0,0 +1,476 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: proto/simulation.proto
+
+package simpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RunParams are the parameters of a run. Parameters that aren't set are
+// taken from the flags of the server, with consecutive seeds.
+type RunParams struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Seed            *int64                 `protobuf:"varint,1,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+	TotalNodes      *int32                 `protobuf:"varint,2,opt,name=total_nodes,json=totalNodes,proto3,oneof" json:"total_nodes,omitempty"`
+	TotalStored     *int32                 `protobuf:"varint,3,opt,name=total_stored,json=totalStored,proto3,oneof" json:"total_stored,omitempty"`
+	GroupSize       *int32                 `protobuf:"varint,4,opt,name=group_size,json=groupSize,proto3,oneof" json:"group_size,omitempty"`
+	NamingStrategy  *string                `protobuf:"bytes,5,opt,name=naming_strategy,json=namingStrategy,proto3,oneof" json:"naming_strategy,omitempty"`
+	BestFitDivisor  *uint64                `protobuf:"varint,6,opt,name=best_fit_divisor,json=bestFitDivisor,proto3,oneof" json:"best_fit_divisor,omitempty"`
+	QuietestDepth   *uint32                `protobuf:"varint,7,opt,name=quietest_depth,json=quietestDepth,proto3,oneof" json:"quietest_depth,omitempty"`
+	SpacingStrategy *string                `protobuf:"bytes,8,opt,name=spacing_strategy,json=spacingStrategy,proto3,oneof" json:"spacing_strategy,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RunParams) Reset() {
+	*x = RunParams{}
+	mi := &file_proto_simulation_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunParams) ProtoMessage() {}
+
+func (x *RunParams) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_simulation_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunParams.ProtoReflect.Descriptor instead.
+func (*RunParams) Descriptor() ([]byte, []int) {
+	return file_proto_simulation_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RunParams) GetSeed() int64 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+func (x *RunParams) GetTotalNodes() int32 {
+	if x != nil && x.TotalNodes != nil {
+		return *x.TotalNodes
+	}
+	return 0
+}
+
+func (x *RunParams) GetTotalStored() int32 {
+	if x != nil && x.TotalStored != nil {
+		return *x.TotalStored
+	}
+	return 0
+}
+
+func (x *RunParams) GetGroupSize() int32 {
+	if x != nil && x.GroupSize != nil {
+		return *x.GroupSize
+	}
+	return 0
+}
+
+func (x *RunParams) GetNamingStrategy() string {
+	if x != nil && x.NamingStrategy != nil {
+		return *x.NamingStrategy
+	}
+	return ""
+}
+
+func (x *RunParams) GetBestFitDivisor() uint64 {
+	if x != nil && x.BestFitDivisor != nil {
+		return *x.BestFitDivisor
+	}
+	return 0
+}
+
+func (x *RunParams) GetQuietestDepth() uint32 {
+	if x != nil && x.QuietestDepth != nil {
+		return *x.QuietestDepth
+	}
+	return 0
+}
+
+func (x *RunParams) GetSpacingStrategy() string {
+	if x != nil && x.SpacingStrategy != nil {
+		return *x.SpacingStrategy
+	}
+	return ""
+}
+
+// RunResult is the balance of storage for a run, or the error that stopped
+// it running, in which case the metrics are zero.
+type RunResult struct {
+	state                     protoimpl.MessageState `protogen:"open.v1"`
+	Error                     string                 `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	StandardDeviation         float64                `protobuf:"fixed64,2,opt,name=standard_deviation,json=standardDeviation,proto3" json:"standard_deviation,omitempty"`
+	RelativeStandardDeviation float64                `protobuf:"fixed64,3,opt,name=relative_standard_deviation,json=relativeStandardDeviation,proto3" json:"relative_standard_deviation,omitempty"`
+	Gini                      float64                `protobuf:"fixed64,4,opt,name=gini,proto3" json:"gini,omitempty"`
+	MaxOverAverage            float64                `protobuf:"fixed64,5,opt,name=max_over_average,json=maxOverAverage,proto3" json:"max_over_average,omitempty"`
+	MinOverAverage            float64                `protobuf:"fixed64,6,opt,name=min_over_average,json=minOverAverage,proto3" json:"min_over_average,omitempty"`
+	unknownFields             protoimpl.UnknownFields
+	sizeCache                 protoimpl.SizeCache
+}
+
+func (x *RunResult) Reset() {
+	*x = RunResult{}
+	mi := &file_proto_simulation_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunResult) ProtoMessage() {}
+
+func (x *RunResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_simulation_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunResult.ProtoReflect.Descriptor instead.
+func (*RunResult) Descriptor() ([]byte, []int) {
+	return file_proto_simulation_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RunResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *RunResult) GetStandardDeviation() float64 {
+	if x != nil {
+		return x.StandardDeviation
+	}
+	return 0
+}
+
+func (x *RunResult) GetRelativeStandardDeviation() float64 {
+	if x != nil {
+		return x.RelativeStandardDeviation
+	}
+	return 0
+}
+
+func (x *RunResult) GetGini() float64 {
+	if x != nil {
+		return x.Gini
+	}
+	return 0
+}
+
+func (x *RunResult) GetMaxOverAverage() float64 {
+	if x != nil {
+		return x.MaxOverAverage
+	}
+	return 0
+}
+
+func (x *RunResult) GetMinOverAverage() float64 {
+	if x != nil {
+		return x.MinOverAverage
+	}
+	return 0
+}
+
+// Job is a submitted run. Status is queued, running, done or failed, and
+// result is set once it is done or failed.
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Params        *RunParams             `protobuf:"bytes,3,opt,name=params,proto3" json:"params,omitempty"`
+	Result        *RunResult             `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_proto_simulation_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_simulation_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_proto_simulation_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Job) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetParams() *RunParams {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *Job) GetResult() *RunResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type JobId struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobId) Reset() {
+	*x = JobId{}
+	mi := &file_proto_simulation_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobId) ProtoMessage() {}
+
+func (x *JobId) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_simulation_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobId.ProtoReflect.Descriptor instead.
+func (*JobId) Descriptor() ([]byte, []int) {
+	return file_proto_simulation_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *JobId) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type JobIds struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []int32                `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobIds) Reset() {
+	*x = JobIds{}
+	mi := &file_proto_simulation_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobIds) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobIds) ProtoMessage() {}
+
+func (x *JobIds) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_simulation_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobIds.ProtoReflect.Descriptor instead.
+func (*JobIds) Descriptor() ([]byte, []int) {
+	return file_proto_simulation_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *JobIds) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+var File_proto_simulation_proto protoreflect.FileDescriptor
+
+const file_proto_simulation_proto_rawDesc = "" +
+	"\n" +
+	"\x16proto/simulation.proto\x12\asafesim\"\xd9\x03\n" +
+	"\tRunParams\x12\x17\n" +
+	"\x04seed\x18\x01 \x01(\x03H\x00R\x04seed\x88\x01\x01\x12$\n" +
+	"\vtotal_nodes\x18\x02 \x01(\x05H\x01R\n" +
+	"totalNodes\x88\x01\x01\x12&\n" +
+	"\ftotal_stored\x18\x03 \x01(\x05H\x02R\vtotalStored\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"group_size\x18\x04 \x01(\x05H\x03R\tgroupSize\x88\x01\x01\x12,\n" +
+	"\x0fnaming_strategy\x18\x05 \x01(\tH\x04R\x0enamingStrategy\x88\x01\x01\x12-\n" +
+	"\x10best_fit_divisor\x18\x06 \x01(\x04H\x05R\x0ebestFitDivisor\x88\x01\x01\x12*\n" +
+	"\x0equietest_depth\x18\a \x01(\rH\x06R\rquietestDepth\x88\x01\x01\x12.\n" +
+	"\x10spacing_strategy\x18\b \x01(\tH\aR\x0fspacingStrategy\x88\x01\x01B\a\n" +
+	"\x05_seedB\x0e\n" +
+	"\f_total_nodesB\x0f\n" +
+	"\r_total_storedB\r\n" +
+	"\v_group_sizeB\x12\n" +
+	"\x10_naming_strategyB\x13\n" +
+	"\x11_best_fit_divisorB\x11\n" +
+	"\x0f_quietest_depthB\x13\n" +
+	"\x11_spacing_strategy\"\xf8\x01\n" +
+	"\tRunResult\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\x12-\n" +
+	"\x12standard_deviation\x18\x02 \x01(\x01R\x11standardDeviation\x12>\n" +
+	"\x1brelative_standard_deviation\x18\x03 \x01(\x01R\x19relativeStandardDeviation\x12\x12\n" +
+	"\x04gini\x18\x04 \x01(\x01R\x04gini\x12(\n" +
+	"\x10max_over_average\x18\x05 \x01(\x01R\x0emaxOverAverage\x12(\n" +
+	"\x10min_over_average\x18\x06 \x01(\x01R\x0eminOverAverage\"\x85\x01\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12*\n" +
+	"\x06params\x18\x03 \x01(\v2\x12.safesim.RunParamsR\x06params\x12*\n" +
+	"\x06result\x18\x04 \x01(\v2\x12.safesim.RunResultR\x06result\"\x17\n" +
+	"\x05JobId\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\x1a\n" +
+	"\x06JobIds\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x05R\x03ids2\x95\x01\n" +
+	"\n" +
+	"Simulation\x12-\n" +
+	"\tSubmitRun\x12\x12.safesim.RunParams\x1a\f.safesim.Job\x12)\n" +
+	"\tGetStatus\x12\x0e.safesim.JobId\x1a\f.safesim.Job\x12-\n" +
+	"\n" +
+	"GetResults\x12\x0f.safesim.JobIds\x1a\f.safesim.Job0\x01BFZDgithub.com/iancoleman/safe_chunk_responsibility_simulation/pkg/simpbb\x06proto3"
+
+var (
+	file_proto_simulation_proto_rawDescOnce sync.Once
+	file_proto_simulation_proto_rawDescData []byte
+)
+
+func file_proto_simulation_proto_rawDescGZIP() []byte {
+	file_proto_simulation_proto_rawDescOnce.Do(func() {
+		file_proto_simulation_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_simulation_proto_rawDesc), len(file_proto_simulation_proto_rawDesc)))
+	})
+	return file_proto_simulation_proto_rawDescData
+}
+
+var file_proto_simulation_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_simulation_proto_goTypes = []any{
+	(*RunParams)(nil), // 0: safesim.RunParams
+	(*RunResult)(nil), // 1: safesim.RunResult
+	(*Job)(nil),       // 2: safesim.Job
+	(*JobId)(nil),     // 3: safesim.JobId
+	(*JobIds)(nil),    // 4: safesim.JobIds
+}
+var file_proto_simulation_proto_depIdxs = []int32{
+	0, // 0: safesim.Job.params:type_name -> safesim.RunParams
+	1, // 1: safesim.Job.result:type_name -> safesim.RunResult
+	0, // 2: safesim.Simulation.SubmitRun:input_type -> safesim.RunParams
+	3, // 3: safesim.Simulation.GetStatus:input_type -> safesim.JobId
+	4, // 4: safesim.Simulation.GetResults:input_type -> safesim.JobIds
+	2, // 5: safesim.Simulation.SubmitRun:output_type -> safesim.Job
+	2, // 6: safesim.Simulation.GetStatus:output_type -> safesim.Job
+	2, // 7: safesim.Simulation.GetResults:output_type -> safesim.Job
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_proto_simulation_proto_init() }
+func file_proto_simulation_proto_init() {
+	if File_proto_simulation_proto != nil {
+		return
+	}
+	file_proto_simulation_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_simulation_proto_rawDesc), len(file_proto_simulation_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_simulation_proto_goTypes,
+		DependencyIndexes: file_proto_simulation_proto_depIdxs,
+		MessageInfos:      file_proto_simulation_proto_msgTypes,
+	}.Build()
+	File_proto_simulation_proto = out.File
+	file_proto_simulation_proto_goTypes = nil
+	file_proto_simulation_proto_depIdxs = nil
+}
@@ -0,0 +1,215 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/simulation.proto
+
+package simpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Simulation_SubmitRun_FullMethodName  = "/safesim.Simulation/SubmitRun"
+	Simulation_GetStatus_FullMethodName  = "/safesim.Simulation/GetStatus"
+	Simulation_GetResults_FullMethodName = "/safesim.Simulation/GetResults"
+)
+
+// SimulationClient is the client API for Simulation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Simulation schedules runs on the machine serving it, the same runs as the
+// params-stdin flag and the http API of the serve subcommand.
+type SimulationClient interface {
+	// SubmitRun queues a run and returns it with its id.
+	SubmitRun(ctx context.Context, in *RunParams, opts ...grpc.CallOption) (*Job, error)
+	// GetStatus returns a submitted run, with its result once done or failed.
+	GetStatus(ctx context.Context, in *JobId, opts ...grpc.CallOption) (*Job, error)
+	// GetResults streams each of the given runs as it finishes, or every run
+	// submitted so far when no ids are given, then ends.
+	GetResults(ctx context.Context, in *JobIds, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error)
+}
+
+type simulationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSimulationClient(cc grpc.ClientConnInterface) SimulationClient {
+	return &simulationClient{cc}
+}
+
+func (c *simulationClient) SubmitRun(ctx context.Context, in *RunParams, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Simulation_SubmitRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulationClient) GetStatus(ctx context.Context, in *JobId, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Simulation_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulationClient) GetResults(ctx context.Context, in *JobIds, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Simulation_ServiceDesc.Streams[0], Simulation_GetResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[JobIds, Job]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Simulation_GetResultsClient = grpc.ServerStreamingClient[Job]
+
+// SimulationServer is the server API for Simulation service.
+// All implementations must embed UnimplementedSimulationServer
+// for forward compatibility.
+//
+// Simulation schedules runs on the machine serving it, the same runs as the
+// params-stdin flag and the http API of the serve subcommand.
+type SimulationServer interface {
+	// SubmitRun queues a run and returns it with its id.
+	SubmitRun(context.Context, *RunParams) (*Job, error)
+	// GetStatus returns a submitted run, with its result once done or failed.
+	GetStatus(context.Context, *JobId) (*Job, error)
+	// GetResults streams each of the given runs as it finishes, or every run
+	// submitted so far when no ids are given, then ends.
+	GetResults(*JobIds, grpc.ServerStreamingServer[Job]) error
+	mustEmbedUnimplementedSimulationServer()
+}
+
+// UnimplementedSimulationServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSimulationServer struct{}
+
+func (UnimplementedSimulationServer) SubmitRun(context.Context, *RunParams) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitRun not implemented")
+}
+func (UnimplementedSimulationServer) GetStatus(context.Context, *JobId) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedSimulationServer) GetResults(*JobIds, grpc.ServerStreamingServer[Job]) error {
+	return status.Errorf(codes.Unimplemented, "method GetResults not implemented")
+}
+func (UnimplementedSimulationServer) mustEmbedUnimplementedSimulationServer() {}
+func (UnimplementedSimulationServer) testEmbeddedByValue()                    {}
+
+// UnsafeSimulationServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SimulationServer will
+// result in compilation errors.
+type UnsafeSimulationServer interface {
+	mustEmbedUnimplementedSimulationServer()
+}
+
+func RegisterSimulationServer(s grpc.ServiceRegistrar, srv SimulationServer) {
+	// If the following call pancis, it indicates UnimplementedSimulationServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Simulation_ServiceDesc, srv)
+}
+
+func _Simulation_SubmitRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulationServer).SubmitRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulation_SubmitRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulationServer).SubmitRun(ctx, req.(*RunParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulation_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulationServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Simulation_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulationServer).GetStatus(ctx, req.(*JobId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulation_GetResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobIds)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SimulationServer).GetResults(m, &grpc.GenericServerStream[JobIds, Job]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Simulation_GetResultsServer = grpc.ServerStreamingServer[Job]
+
+// Simulation_ServiceDesc is the grpc.ServiceDesc for Simulation service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Simulation_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "safesim.Simulation",
+	HandlerType: (*SimulationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitRun",
+			Handler:    _Simulation_SubmitRun_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Simulation_GetStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetResults",
+			Handler:       _Simulation_GetResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/simulation.proto",
+}
@@ -0,0 +1,162 @@
+// Package stats has the summary statistics used to compare how evenly
+// vaults store chunks and how evenly their names are spaced.
+package stats
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// StandardDeviation is the sample standard deviation of numbers that may use
+// all 64 bits, such as spacings between names. It is 0 for fewer than two
+// numbers, which don't vary.
+func StandardDeviation(numbers []uint64) int64 {
+	if len(numbers) < 2 {
+		return 0
+	}
+	avg := Average(numbers)
+	bigAvg := big.NewInt(0).SetUint64(avg)
+	totalDiffs := big.NewInt(0)
+	for _, number := range numbers {
+		bigNumber := big.NewInt(0).SetUint64(number)
+		bigDiff := big.NewInt(0).Sub(bigNumber, bigAvg)
+		bigDiffSquared := big.NewInt(0).Mul(bigDiff, bigDiff)
+		totalDiffs = big.NewInt(0).Add(totalDiffs, bigDiffSquared)
+	}
+	bigDeviation := totalDiffs.Div(totalDiffs, big.NewInt(int64(len(numbers)-1)))
+	return bigDeviation.Sqrt(bigDeviation).Int64()
+}
+
+// StandardDeviationFloat is the sample standard deviation of numbers. It is
+// NaN for fewer than two numbers, as the sample standard deviation is
+// undefined.
+func StandardDeviationFloat(numbers []float64) float64 {
+	avg := AverageFloat(numbers)
+	totalDiffs := 0.0
+	for _, number := range numbers {
+		diff := number - avg
+		totalDiffs += diff * diff
+	}
+	return math.Sqrt(totalDiffs / float64(len(numbers)-1))
+}
+
+// AverageFloat is the mean of numbers, or NaN if there are none.
+func AverageFloat(numbers []float64) float64 {
+	return SumFloat(numbers) / float64(len(numbers))
+}
+
+// SumFloat is the total of numbers.
+func SumFloat(numbers []float64) float64 {
+	total := 0.0
+	for _, number := range numbers {
+		total += number
+	}
+	return total
+}
+
+// Gini is 0 when all numbers are equal, approaching 1 when one holds
+// everything.
+func Gini(numbers []float64) float64 {
+	sorted := append([]float64{}, numbers...)
+	sort.Float64s(sorted)
+	n := float64(len(sorted))
+	total := 0.0
+	weighted := 0.0
+	for i, number := range sorted {
+		total += number
+		weighted += float64(i+1) * number
+	}
+	if total == 0 {
+		return 0
+	}
+	return (2*weighted)/(n*total) - (n+1)/n
+}
+
+// Percentile p of numbers from 0 to 100, using linear interpolation between
+// the closest ranks. It is NaN if there are no numbers, and p outside 0 to
+// 100 is the nearest of them.
+func Percentile(numbers []float64, p float64) float64 {
+	if len(numbers) == 0 {
+		return math.NaN()
+	}
+	p = math.Max(0, math.Min(100, p))
+	sorted := append([]float64{}, numbers...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[upper]-sorted[lower])
+}
+
+// MaxUint64 is the largest of numbers, or 0 if there are none.
+func MaxUint64(numbers []uint64) uint64 {
+	var most uint64 = 0
+	for _, number := range numbers {
+		if number > most {
+			most = number
+		}
+	}
+	return most
+}
+
+// Average is the mean of numbers that may use all 64 bits, rounded down, or
+// 0 if there are none.
+func Average(numbers []uint64) uint64 {
+	if len(numbers) == 0 {
+		return 0
+	}
+	total := big.NewInt(0)
+	for _, number := range numbers {
+		bigNumber := big.NewInt(0).SetUint64(number)
+		total = total.Add(total, bigNumber)
+	}
+	bigLen := big.NewInt(int64(len(numbers)))
+	bigAverage := total.Div(total, bigLen)
+	return bigAverage.Uint64()
+}
+
+// Correlation is the Pearson correlation coefficient of xs and ys, which
+// must be the same length. It is 0 if either does not vary.
+func Correlation(xs, ys []float64) (float64, error) {
+	if len(xs) != len(ys) {
+		return 0, errors.New("Cannot correlate " + strconv.Itoa(len(xs)) + " numbers with " + strconv.Itoa(len(ys)))
+	}
+	avgX := AverageFloat(xs)
+	avgY := AverageFloat(ys)
+	covariance := 0.0
+	varianceX := 0.0
+	varianceY := 0.0
+	for i, _ := range xs {
+		dx := xs[i] - avgX
+		dy := ys[i] - avgY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0, nil
+	}
+	return covariance / math.Sqrt(varianceX*varianceY), nil
+}
+
+// Autocorrelation of numbers with themselves lag places later, from -1 to
+// 1. It is 0 if numbers do not vary.
+func Autocorrelation(numbers []float64, lag int) float64 {
+	avg := AverageFloat(numbers)
+	covariance := 0.0
+	variance := 0.0
+	for i, number := range numbers {
+		variance += (number - avg) * (number - avg)
+		if i+lag < len(numbers) {
+			covariance += (number - avg) * (numbers[i+lag] - avg)
+		}
+	}
+	if variance == 0 {
+		return 0
+	}
+	return covariance / variance
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStandardDeviation(t *testing.T) {
+	if StandardDeviation([]uint64{5, 5, 5}) != 0 {
+		t.Error("Fail standard deviation all equal")
+	}
+	if StandardDeviation([]uint64{1000, 3000, 7000}) != 3055 {
+		t.Error("Fail standard deviation flooring to int")
+	}
+	if StandardDeviation([]uint64{math.MaxUint64, math.MaxUint64 - 99, math.MaxUint64 - 9999}) != 5744 {
+		t.Error("Fail standard deviation very large numbers")
+	}
+	if StandardDeviation([]uint64{7}) != 0 || StandardDeviation(nil) != 0 {
+		t.Error("Fail standard deviation of fewer than two numbers")
+	}
+}
+
+func TestAverage(t *testing.T) {
+	if Average([]uint64{5, 5, 5}) != 5 {
+		t.Error("Fail average all equal")
+	}
+	if Average([]uint64{1000, 3000, 7000}) != 3666 {
+		t.Error("Fail average flooring to int")
+	}
+	if Average([]uint64{math.MaxUint64, math.MaxUint64 - 99, math.MaxUint64 - 9999}) != math.MaxUint64-3366 {
+		t.Error("Fail average very large numbers")
+	}
+	if Average(nil) != 0 {
+		t.Error("Fail average of no numbers")
+	}
+}
+
+func TestFloat(t *testing.T) {
+	floats := []float64{1000, 3000, 7000}
+	if math.Abs(StandardDeviationFloat(floats)-3055.05) > 0.01 {
+		t.Error("Fail float standard deviation")
+	}
+	if math.Abs(AverageFloat(floats)-3666.67) > 0.01 {
+		t.Error("Fail float average")
+	}
+	if !math.IsNaN(StandardDeviationFloat([]float64{7})) {
+		t.Error("Fail float standard deviation of one number")
+	}
+	if !math.IsNaN(AverageFloat(nil)) {
+		t.Error("Fail float average of no numbers")
+	}
+}
+
+func TestGini(t *testing.T) {
+	if Gini([]float64{5, 5, 5}) != 0 {
+		t.Error("Fail gini all equal")
+	}
+	if math.Abs(Gini([]float64{0, 0, 0, 10})-0.75) > 0.0001 {
+		t.Error("Fail gini one holds everything")
+	}
+	if Gini([]float64{0, 0}) != 0 {
+		t.Error("Fail gini nothing stored")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	floats := []float64{1000, 3000, 7000}
+	if Percentile(floats, 50) != 3000 || Percentile(floats, 75) != 5000 {
+		t.Error("Fail percentile")
+	}
+	if Percentile(floats, 0) != 1000 || Percentile(floats, 100) != 7000 {
+		t.Error("Fail percentile at the ends")
+	}
+	if Percentile(floats, -10) != 1000 || Percentile(floats, 110) != 7000 {
+		t.Error("Fail percentile outside 0 to 100")
+	}
+	if !math.IsNaN(Percentile(nil, 50)) {
+		t.Error("Fail percentile of no numbers")
+	}
+}
+
+func TestMaxUint64(t *testing.T) {
+	if MaxUint64([]uint64{3, math.MaxUint64, 5}) != math.MaxUint64 || MaxUint64(nil) != 0 {
+		t.Error("Fail max")
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	// 0 when one set does not vary
+	correlation, err := Correlation([]float64{1, 2, 3}, []float64{2, 4, 6})
+	if err != nil || math.Abs(correlation-1) > 1e-9 {
+		t.Error("Fail correlation rising together")
+	}
+	correlation, err = Correlation([]float64{1, 2, 3}, []float64{3, 2, 1})
+	if err != nil || correlation != -1 {
+		t.Error("Fail correlation opposed")
+	}
+	correlation, err = Correlation([]float64{1, 1, 1}, []float64{1, 2, 3})
+	if err != nil || correlation != 0 {
+		t.Error("Fail correlation not varying")
+	}
+	_, err = Correlation([]float64{1, 2, 3}, []float64{1, 2})
+	if err == nil {
+		t.Error("Fail correlation of different lengths")
+	}
+}
+
+func TestAutocorrelation(t *testing.T) {
+	// loads alternate so neighbours are opposed
+	alternating := []float64{1, 3, 1, 3}
+	if Autocorrelation(alternating, 1) != -0.75 || Autocorrelation(alternating, 2) != 0.5 {
+		t.Error("Fail autocorrelation alternating")
+	}
+	if Autocorrelation([]float64{2, 2}, 1) != 0 {
+		t.Error("Fail autocorrelation not varying")
+	}
+}
@@ -0,0 +1,60 @@
+package strategy_test
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/iancoleman/safe_chunk_responsibility_simulation/pkg/strategy"
+)
+
+func ExampleRandomNameBetween() {
+	// every strategy picks its name this way once it has chosen a space
+	rng := rand.New(rand.NewSource(1))
+	name := strategy.RandomNameBetween(rng, 0x1000000000000000, 0x1fffffffffffffff)
+	fmt.Printf("%016x\n", name)
+	// Output:
+	// 1d65822107fcfd52
+}
+
+func ExampleNameForBestFit() {
+	// the biggest space is from 5000... to c000..., and a divisor of 3
+	// keeps the name in its middle third, from 7555... to 9aaa...
+	rng := rand.New(rand.NewSource(1))
+	names := []uint64{0x4000000000000000, 0x5000000000000000, 0xc000000000000000}
+	name := strategy.NameForBestFit(rng, names, 3, strategy.LinearSpacing)
+	fmt.Printf("%016x\n", name)
+	// Output:
+	// 78102ccbb2a7a7f9
+}
+
+func ExampleNameForQuietestHalf() {
+	// three names start with a 0 bit and one with a 1 bit, so the next
+	// name goes in the upper half
+	rng := rand.New(rand.NewSource(1))
+	names := []uint64{0x1000000000000000, 0x2000000000000000, 0x3000000000000000, 0x9000000000000000}
+	vaults, err := strategy.CountSubsectionVaults(names, 1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	name, err := strategy.NameForQuietestHalf(rng, vaults, 1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(vaults)
+	fmt.Printf("%016x\n", name)
+	// Output:
+	// [3 1]
+	// cd65822107fcfd52
+}
+
+func ExampleNameForEmptySubsection() {
+	// every half has a name, but the quarter starting 11 is empty
+	rng := rand.New(rand.NewSource(1))
+	names := []uint64{0x1000000000000000, 0x5000000000000000, 0x9000000000000000}
+	name := strategy.NameForEmptySubsection(rng, names)
+	fmt.Printf("%016x\n", name)
+	// Output:
+	// f8629a0f5f3f164f
+}
@@ -0,0 +1,204 @@
+// Package strategy has the ways a joining vault can be given a name, and the
+// ways spacing between names is measured. Names are the leading 64 bits of
+// an XorName. Randomness comes from the caller so a seeded run is
+// repeatable.
+package strategy
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"math/rand"
+	"strconv"
+)
+
+// NameTrie is a binary prefix trie of vault names, where the child for bit
+// 0 or 1 is nil if no name continues the prefix with that bit.
+type NameTrie struct {
+	Children [2]*NameTrie
+}
+
+// SpacingFunc measures the space between two names, where bigName is the
+// later of the two.
+type SpacingFunc func(bigName, smallName uint64) uint64
+
+// LinearSpacing is the difference between two names.
+func LinearSpacing(bigName, smallName uint64) uint64 {
+	return bigName - smallName
+}
+
+// XorDistanceSpacing is the xor distance between two names.
+func XorDistanceSpacing(bigName, smallName uint64) uint64 {
+	return bigName ^ smallName
+}
+
+// Spacing returns how spacing is measured by strategy, "linear" or
+// "xordistance", or an error for any other strategy.
+func Spacing(strategy string) (SpacingFunc, error) {
+	if strategy == "linear" {
+		return LinearSpacing, nil
+	} else if strategy == "xordistance" {
+		return XorDistanceSpacing, nil
+	}
+	return nil, errors.New("Invalid spacing strategy " + strategy)
+}
+
+// RandomNameBetween returns a random name from minName to maxName inclusive.
+func RandomNameBetween(rng *rand.Rand, minName, maxName uint64) uint64 {
+	if minName > maxName {
+		minName, maxName = maxName, minName
+	}
+	span := maxName - minName
+	if span == math.MaxUint64 {
+		return rng.Uint64()
+	}
+	return minName + rng.Uint64()%(span+1)
+}
+
+// NameForBestFit returns a name in the biggest space between names, which
+// are in order. The name is kept away from either side of the space by
+// 1/divisor of it, or anywhere in the space for a divisor of 0.
+func NameForBestFit(rng *rand.Rand, names []uint64, divisor uint64, spacing SpacingFunc) uint64 {
+	// get the maximum spacing between existing names
+	var maxSpacing uint64
+	var minName uint64
+	var maxName uint64
+	// if this is the first node
+	// the name must be between 0 and MaxUint64
+	if len(names) == 0 {
+		maxSpacing = math.MaxUint64
+		minName = 0
+		maxName = math.MaxUint64
+	} else {
+		// find the maximum space between names
+		for i, _ := range names {
+			thisName := names[i]
+			var previousName uint64 = 0
+			if i > 0 {
+				previousName = names[i-1]
+			}
+			thisSpacing := spacing(thisName, previousName)
+			if thisSpacing > maxSpacing {
+				maxSpacing = thisSpacing
+				minName = previousName
+				maxName = thisName
+			}
+		}
+		// check the space between the last node and MaxUint64
+		lastName := names[len(names)-1]
+		lastSpacing := spacing(math.MaxUint64, lastName)
+		if lastSpacing > maxSpacing {
+			maxSpacing = lastSpacing
+			minName = lastName
+			maxName = math.MaxUint64
+		}
+	}
+	// adjust the names to be in a more precise gap
+	// https://safenetforum.org/t/chunk-distribution-within-sections/29187/34
+	if divisor > 0 {
+		minName = minName + (maxSpacing / divisor)
+		maxName = maxName - (maxSpacing / divisor)
+	}
+	// find a new name within this spacing
+	return RandomNameBetween(rng, minName, maxName)
+}
+
+// NameForQuietestHalf returns a name in the subsection with the least
+// vaults, where vaults is the count in each subsection at depth, eg halves
+// at depth 1.
+func NameForQuietestHalf(rng *rand.Rand, vaults []int, depth uint) (uint64, error) {
+	if len(vaults) == 0 {
+		return 0, errors.New("No subsections to choose a name in")
+	}
+	quietest := 0
+	for i, count := range vaults {
+		if count < vaults[quietest] {
+			quietest = i
+		}
+	}
+	// find a new name within this subsection
+	var subsectionSize uint64 = math.MaxUint64 >> depth
+	minName := uint64(quietest) << (64 - depth)
+	maxName := minName + subsectionSize
+	return RandomNameBetween(rng, minName, maxName), nil
+}
+
+// NameForEmptySubsection returns a name in one of the biggest subsections
+// that has no name in it.
+func NameForEmptySubsection(rng *rand.Rand, names []uint64) uint64 {
+	// Find all empty subsections, starting with the biggest subsection and
+	// progressively testing smaller subsections. A subsection is empty
+	// where the trie of names has no child, so subsections of every name
+	// are never enumerated. Some subsection at depth d is empty once there
+	// are fewer than 2^d names, which limits how deep the trie goes.
+	maxDepth := uint(bits.Len(uint(len(names))))
+	trie := BuildNameTrie(names, maxDepth)
+	emptySubsections := [][]uint64{}
+	if len(names) == 0 {
+		emptySubsections = append(emptySubsections, []uint64{0, math.MaxUint64})
+	}
+	// tries at the previous depth, with their prefix
+	level := []*NameTrie{trie}
+	prefixes := []uint64{0}
+	for depth := uint(1); len(emptySubsections) == 0 && depth <= maxDepth; depth++ {
+		var subsectionSize uint64 = math.MaxUint64 >> depth
+		nextLevel := []*NameTrie{}
+		nextPrefixes := []uint64{}
+		for i, t := range level {
+			for bit := uint64(0); bit < 2; bit++ {
+				prefix := prefixes[i]<<1 | bit
+				if t.Children[bit] == nil {
+					start := prefix << (64 - depth)
+					subsection := []uint64{start, start + subsectionSize}
+					emptySubsections = append(emptySubsections, subsection)
+				} else {
+					nextLevel = append(nextLevel, t.Children[bit])
+					nextPrefixes = append(nextPrefixes, prefix)
+				}
+			}
+		}
+		level = nextLevel
+		prefixes = nextPrefixes
+	}
+	// every empty subsection is the same size, so choosing one and then a
+	// name within it is the same as a random name in any of them
+	subsection := emptySubsections[rng.Intn(len(emptySubsections))]
+	return RandomNameBetween(rng, subsection[0], subsection[1])
+}
+
+// BuildNameTrie adds the leading depth bits of each name to a trie.
+func BuildNameTrie(names []uint64, depth uint) *NameTrie {
+	root := &NameTrie{}
+	for _, name := range names {
+		t := root
+		for d := uint(0); d < depth; d++ {
+			bit := (name >> (63 - d)) & 1
+			if t.Children[bit] == nil {
+				t.Children[bit] = &NameTrie{}
+			}
+			t = t.Children[bit]
+		}
+	}
+	return root
+}
+
+// CountSubsectionVaults returns how many names are in each subsection at
+// depth, in order of prefix. Depth must be less than 64.
+func CountSubsectionVaults(names []uint64, depth uint) ([]int, error) {
+	if depth >= 64 {
+		return nil, errors.New("Invalid subsection depth " + strconv.Itoa(int(depth)))
+	}
+	vaults := make([]int, uint64(1)<<depth)
+	for _, name := range names {
+		vaults[SubsectionOf(name, depth)] += 1
+	}
+	return vaults, nil
+}
+
+// SubsectionOf is the prefix of name at depth.
+func SubsectionOf(name uint64, depth uint) uint64 {
+	if depth == 0 {
+		return 0
+	}
+	return name >> (64 - depth)
+}
@@ -0,0 +1,142 @@
+package strategy
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSpacing(t *testing.T) {
+	linear, err := Spacing("linear")
+	if err != nil || linear(0x30, 0x10) != 0x20 {
+		t.Error("Fail linear spacing")
+	}
+	xor, err := Spacing("xordistance")
+	if err != nil || xor(0x30, 0x10) != 0x20 || xor(0x8, 0x7) != 0xF {
+		t.Error("Fail xor distance spacing")
+	}
+	_, err = Spacing("nope")
+	if err == nil {
+		t.Error("Fail unknown spacing strategy")
+	}
+}
+
+func TestRandomNameBetween(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		// the bounds can be either way round
+		name := RandomNameBetween(rng, 0x20, 0x10)
+		if name < 0x10 || name > 0x20 {
+			t.Fatal("Name between is outside the bounds")
+		}
+	}
+	if RandomNameBetween(rng, 5, 5) != 5 {
+		t.Error("Name between equal bounds is wrong")
+	}
+}
+
+func TestNameForEmptySubsection(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	emptyA := []uint64{
+		0x4000000000000000,
+		0x5000000000000000 - 1,
+	}
+	emptyB := []uint64{
+		0xB000000000000000,
+		0xC000000000000000 - 1,
+	}
+	names := []uint64{
+		0x0000000000003000,
+		0x1000000000003000,
+		0x2000000000003000,
+		0x3000000000003000,
+		//0x4000000000003000,
+		0x5000000000003000,
+		0x6000000000003000,
+		0x7000000000003000,
+		0x8000000000003000,
+		0x9000000000003000,
+		0xA000000000003000,
+		//0xB000000000003000,
+		0xC000000000003000,
+		0xD000000000003000,
+		0xE000000000003000,
+		0xF000000000003000,
+	}
+	name := NameForEmptySubsection(rng, names)
+	if !((name >= emptyA[0] && name <= emptyA[1]) || (name >= emptyB[0] && name <= emptyB[1])) {
+		t.Error("Name for empty subsection is wrong")
+	}
+	// every quarter has a vault, so the name is in the empty second half of
+	// one of them
+	name = NameForEmptySubsection(rng, []uint64{0x0, 0x4000000000000000, 0x8000000000000000, 0xC000000000000000})
+	if name&0x2000000000000000 == 0 {
+		t.Error("Name for deeper empty subsection is wrong")
+	}
+	// the first name can be anywhere
+	NameForEmptySubsection(rng, nil)
+}
+
+func TestNameForBestFit(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// names land in the middle third of the largest gap, which is from 0x4
+	// to the end of the name space
+	names := []uint64{0x0, 0x4000000000000000}
+	for i := 0; i < 100; i++ {
+		name := NameForBestFit(rng, names, 3, LinearSpacing)
+		if name < 0x7FFFFFFFFFFFFFFF || name > 0xC000000000000000 {
+			t.Fatal("Name for best fit is outside the largest gap")
+		}
+	}
+	// a divisor of 0 uses the whole gap
+	for i := 0; i < 100; i++ {
+		name := NameForBestFit(rng, names, 0, LinearSpacing)
+		if name < 0x4000000000000000 {
+			t.Fatal("Name for best fit with divisor 0 is outside the largest gap")
+		}
+	}
+	if NameForBestFit(rng, nil, 3, XorDistanceSpacing) > math.MaxUint64/3*2+1 {
+		t.Error("Name for best fit of the first vault is outside the middle third")
+	}
+}
+
+func TestNameForQuietestHalf(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	names := []uint64{
+		0x1000000000000000,
+		0x5000000000000000,
+		0x9000000000000000,
+	}
+	// names land in the half with fewer vaults
+	halves, _ := CountSubsectionVaults(names, 1)
+	for i := 0; i < 100; i++ {
+		name, err := NameForQuietestHalf(rng, halves, 1)
+		if err != nil || name < 0x7FFFFFFFFFFFFFFF {
+			t.Fatal("Name for quietest half is in the busier half")
+		}
+	}
+	// and at depth 2 in the only empty quarter
+	quarters, _ := CountSubsectionVaults(names, 2)
+	name, err := NameForQuietestHalf(rng, quarters, 2)
+	if err != nil || name < 0xC000000000000000 {
+		t.Error("Name for quietest subsection is wrong")
+	}
+	_, err = NameForQuietestHalf(rng, nil, 1)
+	if err == nil {
+		t.Error("Fail quietest half without subsections")
+	}
+}
+
+func TestCountSubsectionVaults(t *testing.T) {
+	vaults, err := CountSubsectionVaults([]uint64{0x1, 0x7FFFFFFFFFFFFFFF, 0x8000000000000000}, 1)
+	if err != nil || len(vaults) != 2 || vaults[0] != 2 || vaults[1] != 1 {
+		t.Error("Fail subsection counts")
+	}
+	_, err = CountSubsectionVaults([]uint64{0x1}, 64)
+	if err == nil {
+		t.Error("Fail subsection counts too deep")
+	}
+	if SubsectionOf(0xC000000000000000, 2) != 3 || SubsectionOf(0xC000000000000000, 0) != 0 {
+		t.Error("Fail subsection of name")
+	}
+}